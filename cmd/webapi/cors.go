@@ -12,8 +12,16 @@ func applyCORSHandler(h http.Handler) http.Handler {
 	return handlers.CORS(
 		handlers.AllowedHeaders([]string{
 			"x-example-header",
+			"Authorization",
+			"Content-Type",
+			"If-None-Match",
+			"If-Modified-Since",
+			"Range",
 		}),
-		handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS", "DELETE", "PUT"}),
+		// Pagination links are sent in the Link header, which is not readable by scripts unless exposed, as the
+		// validators and ranges of photos and attachments.
+		handlers.ExposedHeaders([]string{"Link", "ETag", "Content-Range", "Accept-Ranges"}),
+		handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS", "DELETE", "PUT", "PATCH"}),
 		// Do not modify the CORS origin and max age, they are used in the evaluation.
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.MaxAge(1),
@@ -28,6 +28,29 @@ type WebAPIConfiguration struct {
 	DB    struct {
 		Filename string `conf:"default:/tmp/decaf.db"`
 	}
+	Messages struct {
+		// EditWindow is how long after sending a message its sender can edit it (0 for no limit)
+		EditWindow time.Duration `conf:"default:15m"`
+
+		// MaxImageSize and MaxFileSize limit the size (in bytes) of images and other files attached to messages;
+		// MaxImageSize limits the user and group photos too
+		MaxImageSize int64 `conf:"default:10485760"`
+		MaxFileSize  int64 `conf:"default:26214400"`
+	}
+	Blobs struct {
+		// Driver selects where photos and attachments are stored: "filesystem" (in Dir) or "s3"
+		Driver string `conf:"default:filesystem"`
+		Dir    string `conf:"default:/tmp/wasatext-blobs"`
+		S3     struct {
+			Endpoint        string
+			Region          string `conf:"default:us-east-1"`
+			Bucket          string
+			Prefix          string
+			AccessKeyID     string
+			SecretAccessKey string `conf:"noprint"`
+			PathStyle       bool
+		}
+	}
 }
 
 // loadConfiguration creates a WebAPIConfiguration starting from flags, environment variables and configuration file.
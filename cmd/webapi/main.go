@@ -18,6 +18,11 @@ Return values (exit codes):
 	> 0
 		The program ended due to an error
 
+Build it with FTS5 in the SQLite driver, for the full-text search of the messages (without it, messages are searched
+with simple text matching, and a database indexed with FTS5 can't be used without it anymore):
+
+	go build -tags sqlite_fts5 ./cmd/webapi
+
 Note that this program will update the schema of the database to the latest version available (embedded in the
 executable during the build).
 */
@@ -84,7 +89,8 @@ func run() error {
 
 	// Start Database
 	logger.Println("initializing database support")
-	dbconn, err := sql.Open("sqlite3", cfg.DB.Filename)
+	// Foreign keys are enforced by SQLite only when enabled for each connection
+	dbconn, err := sql.Open("sqlite3", cfg.DB.Filename+"?_foreign_keys=on&_busy_timeout=5000")
 	if err != nil {
 		logger.WithError(err).Error("error opening SQLite DB")
 		return fmt.Errorf("opening SQLite: %w", err)
@@ -99,6 +105,21 @@ func run() error {
 		return fmt.Errorf("creating AppDatabase: %w", err)
 	}
 
+	// Open the blob store, and move there the content saved in the database by older versions
+	logger.Infof("initializing %s blob store", cfg.Blobs.Driver)
+	blobs, err := openBlobStore(cfg)
+	if err != nil {
+		logger.WithError(err).Error("error opening the blob store")
+		return fmt.Errorf("opening the blob store: %w", err)
+	}
+	moved, err := db.MoveLegacyBlobs(context.Background(), blobs.Put)
+	if err != nil {
+		logger.WithError(err).Error("error moving blobs to the blob store")
+		return fmt.Errorf("moving blobs to the blob store: %w", err)
+	} else if moved > 0 {
+		logger.Infof("%d blobs moved to the blob store", moved)
+	}
+
 	// Start (main) API server
 	logger.Info("initializing API server")
 
@@ -113,8 +134,12 @@ func run() error {
 
 	// Create the API router
 	apirouter, err := api.New(api.Config{
-		Logger:   logger,
-		Database: db,
+		Logger:       logger,
+		Database:     db,
+		Blobs:        blobs,
+		EditWindow:   cfg.Messages.EditWindow,
+		MaxImageSize: cfg.Messages.MaxImageSize,
+		MaxFileSize:  cfg.Messages.MaxFileSize,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/PrinceLM1013/WasaText/service/blobstore"
+)
+
+// openBlobStore returns the blob store selected in the configuration.
+func openBlobStore(cfg WebAPIConfiguration) (blobstore.BlobStore, error) {
+	switch cfg.Blobs.Driver {
+	case "filesystem":
+		return blobstore.NewFilesystem(cfg.Blobs.Dir)
+	case "s3":
+		return blobstore.NewS3(blobstore.S3Config{
+			Endpoint:        cfg.Blobs.S3.Endpoint,
+			Region:          cfg.Blobs.S3.Region,
+			Bucket:          cfg.Blobs.S3.Bucket,
+			Prefix:          cfg.Blobs.S3.Prefix,
+			AccessKeyID:     cfg.Blobs.S3.AccessKeyID,
+			SecretAccessKey: cfg.Blobs.S3.SecretAccessKey,
+			PathStyle:       cfg.Blobs.S3.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store driver %q", cfg.Blobs.Driver)
+	}
+}
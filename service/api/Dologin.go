@@ -4,30 +4,30 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) Dologin(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func (rt *_router) Dologin(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
-	var user struct {
-		ID   string `json:"id"`
+	var request struct {
 		Name string `json:"name"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Validate user ID and name
-	if user.ID == "" || user.Name == "" {
-		http.Error(w, "ID and name are required", http.StatusBadRequest)
+	// Validate the name
+	if request.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
 
 	// Check if the user exists or create a new one
-	userID, err := rt.db.GetOrCreateUser(user.ID, user.Name)
+	user, err := rt.db.GetOrCreateUser(r.Context(), request.Name)
 	if err != nil {
-		http.Error(w, "Failed to create or retrieve user", http.StatusInternalServerError)
+		replyError(w, ctx, err, "Failed to create or retrieve user")
 		return
 	}
 
@@ -35,6 +35,6 @@ func (rt *_router) Dologin(w http.ResponseWriter, r *http.Request, _ httprouter.
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{
-		"identifier": userID,
+		"identifier": user.ID,
 	})
 }
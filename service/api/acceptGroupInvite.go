@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) acceptGroupInvite(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve invite token from route parameters
+	token := ps.ByName("token")
+
+	// Join the group of the invite
+	group, message, err := rt.db.AcceptGroupInvite(r.Context(), ctx.UserID, token)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to accept invite")
+		return
+	}
+	if user, err := rt.db.GetUser(r.Context(), ctx.UserID); err == nil {
+		rt.notifyConversation(group.ID, eventMemberAdded, user)
+	}
+	rt.notifyConversation(group.ID, eventMessageCreated, message)
+
+	// Respond with the group joined
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
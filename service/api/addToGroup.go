@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) addToGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) addToGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
 	var request struct {
 		UserID string `json:"userId"`
@@ -27,10 +28,15 @@ func (rt *_router) addToGroup(w http.ResponseWriter, r *http.Request, ps httprou
 	groupID := ps.ByName("id")
 
 	// Add the user to the group
-	if err := rt.db.AddUserToGroup(groupID, request.UserID); err != nil {
-		http.Error(w, "Failed to add user to group", http.StatusInternalServerError)
+	message, err := rt.db.AddUserToGroup(r.Context(), ctx.UserID, groupID, request.UserID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to add user to group")
 		return
 	}
+	if user, err := rt.db.GetUser(r.Context(), request.UserID); err == nil {
+		rt.notifyConversation(groupID, eventMemberAdded, user)
+	}
+	rt.notifyConversation(groupID, eventMessageCreated, message)
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
 	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
@@ -13,8 +16,21 @@ import (
 // required by the httprouter package.
 type httpRouterHandler func(http.ResponseWriter, *http.Request, httprouter.Params, reqcontext.RequestContext)
 
-// wrap parses the request and adds a reqcontext.RequestContext instance related to the request.
-func (rt *_router) wrap(fn httpRouterHandler) func(http.ResponseWriter, *http.Request, httprouter.Params) {
+// access tells wrap whether a route can be called without being authenticated.
+type access int
+
+const (
+	// authenticated routes require a valid `Authorization: Bearer <identifier>` header (BearerAuth in doc/api.yaml).
+	authenticated access = iota
+
+	// public routes can be called by anyone (e.g., the login).
+	public
+)
+
+// wrap parses the request and adds a reqcontext.RequestContext instance related to the request. For authenticated
+// routes, the bearer identifier is resolved against the database and the request is rejected with 401 Unauthorized if
+// it does not match any user.
+func (rt *_router) wrap(fn httpRouterHandler, acc access) func(http.ResponseWriter, *http.Request, httprouter.Params) {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		reqUUID, err := uuid.NewV4()
 		if err != nil {
@@ -32,7 +48,43 @@ func (rt *_router) wrap(fn httpRouterHandler) func(http.ResponseWriter, *http.Re
 			"remote-ip": r.RemoteAddr,
 		})
 
+		if acc == authenticated {
+			userID, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Missing or malformed bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			_, err := rt.db.GetUser(r.Context(), userID)
+			if errors.Is(err, database.ErrNotFound) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid bearer token", http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				ctx.Logger.WithError(err).Error("can't check the bearer identifier")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			ctx.UserID = userID
+			ctx.Logger = ctx.Logger.WithField("user", userID)
+		}
+
+		// Make the request context available also to code receiving only the *http.Request
+		r = r.WithContext(reqcontext.NewContext(r.Context(), ctx))
+
 		// Call the next handler in chain (usually, the handler function for the path)
 		fn(w, r, ps, ctx)
 	}
 }
+
+// bearerToken extracts the identifier from the `Authorization: Bearer <identifier>` header.
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+	token := strings.TrimSpace(parts[1])
+	return token, token != ""
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestWrapAuthentication(t *testing.T) {
+	rt := newTestRouter(t)
+	alice, err := rt.db.GetOrCreateUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The handler gets the user of the bearer identifier, also from the context of the request
+	var called bool
+	handler := rt.wrap(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+		called = true
+		if ctx.UserID != alice.ID {
+			t.Errorf("UserID = %q, want %q", ctx.UserID, alice.ID)
+		}
+		if rc, ok := reqcontext.FromContext(r.Context()); !ok || rc.UserID != alice.ID {
+			t.Errorf("request context = %+v, %v, want the user %s", rc, ok, alice.ID)
+		}
+	}, authenticated)
+	for _, header := range []string{"Bearer " + alice.ID, "bearer  " + alice.ID + " "} {
+		called = false
+		r := httptest.NewRequest(http.MethodGet, "/conversations", nil)
+		r.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		handler(w, r, nil)
+		if !called {
+			t.Errorf("Authorization %q: %d %s, want the handler called", header, w.Code, w.Body.String())
+		}
+	}
+
+	// Missing, malformed or unknown identifiers are rejected before reaching the handler
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"", "Bearer"},
+		{alice.ID, "Bearer"},
+		{"Basic " + alice.ID, "Bearer"},
+		{"Bearer ", "Bearer"},
+		{"Bearer nonexistent0", `Bearer error="invalid_token"`},
+	} {
+		called = false
+		r := httptest.NewRequest(http.MethodGet, "/conversations", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r, nil)
+		if called || w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != tt.want {
+			t.Errorf("Authorization %q: %d, WWW-Authenticate %q, want 401 with %q", tt.header, w.Code,
+				w.Header().Get("WWW-Authenticate"), tt.want)
+		}
+	}
+
+	// Public routes don't need it
+	called = false
+	login := rt.wrap(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+		called = ctx.UserID == ""
+	}, public)
+	login(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/session", strings.NewReader("{}")), nil)
+	if !called {
+		t.Error("public route not called without a user")
+	}
+}
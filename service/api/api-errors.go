@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+)
+
+// errorStatus maps an error returned by the database package (or by the shared logic in messaging.go) to the matching
+// HTTP status code. Unexpected errors are mapped to 500 Internal Server Error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, database.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, database.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// replyError sends the status code matching err (see errorStatus) to the client, with msg as the only explanation: the
+// details wrapped in err (identifiers, invite tokens, ...) are for the logs. Unexpected errors are logged as errors, the
+// others at debug level.
+func replyError(w http.ResponseWriter, ctx reqcontext.RequestContext, err error, msg string) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		ctx.Logger.WithError(err).Error(msg)
+	} else {
+		ctx.Logger.WithError(err).WithField("status", status).Debug(msg)
+	}
+	http.Error(w, msg, status)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReplyError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := reqcontext.RequestContext{Logger: logger}
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("invite qC4rQmNBacEBuI6P has been revoked: %w", database.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("not a member of conversation abcdef012345: %w", database.ErrForbidden), http.StatusForbidden},
+		{fmt.Errorf("already in group abcdef012345: %w", database.ErrConflict), http.StatusConflict},
+		{fmt.Errorf("limit out of range: %w", errBadRequest), http.StatusBadRequest},
+		{fmt.Errorf("attachment too large: %w", errTooLarge), http.StatusRequestEntityTooLarge},
+		{errors.New("disk I/O error in /var/lib/wasatext"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		replyError(w, ctx, tt.err, "Failed to do it")
+		if w.Code != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, w.Code, tt.status)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != "Failed to do it" {
+			t.Errorf("%v: body = %q, want only the message", tt.err, body)
+		}
+	}
+}
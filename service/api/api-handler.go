@@ -6,34 +6,66 @@ import (
 
 // Handler returns an instance of httprouter.Router that handle APIs registered here
 func (rt *_router) Handler() http.Handler {
-	// Register routes
+	// Register routes. Every route wrapped with `authenticated` requires the BearerAuth header, while `public` ones are
+	// open to anonymous users.
 	rt.router.GET("/", rt.getHelloWorld)
-	rt.router.GET("/context", rt.wrap(rt.getContextReply))
+	rt.router.GET("/context", rt.wrap(rt.getContextReply, public))
+
+	// Event stream (Server-Sent Events) and WebSocket gateway
+	rt.router.GET("/events", rt.wrap(rt.getEvents, authenticated))
+	rt.router.GET("/ws", rt.wrap(rt.serveWebSocket, authenticated))
 
 	// Special routes
-	rt.router.GET("/liveness", rt.liveness)
+	rt.router.GET("/liveness", rt.wrap(rt.liveness, public))
 
 	// User routes
-	rt.router.POST("/session", rt.wrap(rt.Dologin))
-	rt.router.PUT("/users/me/name", rt.wrap(rt.setMyUserName))
-	rt.router.PUT("/users/me/photo", rt.wrap(rt.setMyPhoto))
+	rt.router.POST("/session", rt.wrap(rt.Dologin, public))
+	rt.router.GET("/users", rt.wrap(rt.searchUsers, authenticated))
+	rt.router.PUT("/users/me/name", rt.wrap(rt.setMyUserName, authenticated))
+	rt.router.PUT("/users/me/photo", rt.wrap(rt.setMyPhoto, authenticated))
+	rt.router.GET("/users/:id/photo", rt.wrap(rt.getUserPhoto, authenticated))
+	// GET /users/me/starred would conflict with the wildcard above: the handler accepts only "me"
+	rt.router.GET("/users/:id/starred", rt.wrap(rt.getStarredMessages, authenticated))
 
 	// Conversation routes
-	rt.router.GET("/conversations", rt.wrap(rt.getMyConversations))
-	rt.router.GET("/conversations/:id", rt.wrap(rt.getConversation))
+	rt.router.GET("/conversations", rt.wrap(rt.getMyConversations, authenticated))
+	rt.router.POST("/conversations", rt.wrap(rt.createConversation, authenticated))
+	rt.router.GET("/conversations/:id", rt.wrap(rt.getConversation, authenticated))
+	rt.router.POST("/conversations/:id/read", rt.wrap(rt.markConversationRead, authenticated))
+	rt.router.PUT("/conversations/:id/timer", rt.wrap(rt.setMessageTimer, authenticated))
+	rt.router.GET("/conversations/:id/pins", rt.wrap(rt.getPinnedMessages, authenticated))
 
 	// Message routes
-	rt.router.POST("/messages", rt.wrap(rt.sendMessage))
-	rt.router.POST("/messages/:id/forward", rt.wrap(rt.forwardMessage))
-	rt.router.POST("/messages/:id/comment", rt.wrap(rt.commentMessage))
-	rt.router.DELETE("/messages/:id/comment", rt.wrap(rt.uncommentMessage))
-	rt.router.DELETE("/messages/:id/delete", rt.wrap(rt.deleteMessage))
+	rt.router.POST("/messages", rt.wrap(rt.sendMessage, authenticated))
+	rt.router.PATCH("/messages/:id", rt.wrap(rt.editMessage, authenticated))
+	rt.router.GET("/messages/:id/history", rt.wrap(rt.getMessageHistory, authenticated))
+	rt.router.GET("/attachments/:id", rt.wrap(rt.getAttachment, authenticated))
+	rt.router.POST("/messages/:id/forward", rt.wrap(rt.forwardMessage, authenticated))
+	rt.router.POST("/messages/:id/comment", rt.wrap(rt.commentMessage, authenticated))
+	rt.router.DELETE("/messages/:id/comment/:emoji", rt.wrap(rt.uncommentMessage, authenticated))
+	rt.router.DELETE("/messages/:id/delete", rt.wrap(rt.deleteMessage, authenticated))
+	rt.router.POST("/messages/:id/pin", rt.wrap(rt.pinMessage, authenticated))
+	rt.router.DELETE("/messages/:id/pin", rt.wrap(rt.unpinMessage, authenticated))
+	rt.router.POST("/messages/:id/star", rt.wrap(rt.starMessage, authenticated))
+	rt.router.DELETE("/messages/:id/star", rt.wrap(rt.unstarMessage, authenticated))
+	rt.router.GET("/search", rt.wrap(rt.searchMessages, authenticated))
+	rt.router.GET("/scheduled-messages", rt.wrap(rt.getScheduledMessages, authenticated))
+	rt.router.DELETE("/scheduled-messages/:id", rt.wrap(rt.deleteScheduledMessage, authenticated))
 
 	// Group routes
-	rt.router.POST("/groups/:id/leave", rt.wrap(rt.leaveGroup))
-	rt.router.PUT("/groups/:id/photo", rt.wrap(rt.setGroupPhoto))
-	rt.router.POST("/groups/:id/add", rt.wrap(rt.addToGroup))
-	rt.router.PUT("/groups/:id/name", rt.wrap(rt.setGroupName))
+	rt.router.POST("/groups", rt.wrap(rt.createGroup, authenticated))
+	rt.router.POST("/groups/:id/leave", rt.wrap(rt.leaveGroup, authenticated))
+	rt.router.PUT("/groups/:id/photo", rt.wrap(rt.setGroupPhoto, authenticated))
+	rt.router.GET("/groups/:id/photo", rt.wrap(rt.getGroupPhoto, authenticated))
+	rt.router.POST("/groups/:id/add", rt.wrap(rt.addToGroup, authenticated))
+	rt.router.PUT("/groups/:id/name", rt.wrap(rt.setGroupName, authenticated))
+	rt.router.POST("/groups/:id/members/:userId/promote", rt.wrap(rt.promoteGroupMember, authenticated))
+	rt.router.POST("/groups/:id/members/:userId/demote", rt.wrap(rt.demoteGroupMember, authenticated))
+	rt.router.DELETE("/groups/:id/members/:userId", rt.wrap(rt.removeGroupMember, authenticated))
+	rt.router.POST("/groups/:id/invites", rt.wrap(rt.createGroupInvite, authenticated))
+	rt.router.GET("/groups/:id/invites", rt.wrap(rt.getGroupInvites, authenticated))
+	rt.router.DELETE("/groups/:id/invites/:token", rt.wrap(rt.revokeGroupInvite, authenticated))
+	rt.router.POST("/invites/:token/accept", rt.wrap(rt.acceptGroupInvite, authenticated))
 
 	return rt.router
 }
@@ -11,6 +11,7 @@ Example:
 	apirouter, err := api.New(api.Config{
 		Logger:   logger,
 		Database: appdb,
+		Blobs:    blobs,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
@@ -39,7 +40,10 @@ package api
 import (
 	"errors"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/PrinceLM1013/WasaText/service/blobstore"
 	"github.com/PrinceLM1013/WasaText/service/database"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
@@ -52,6 +56,18 @@ type Config struct {
 
 	// Database is the instance of database.AppDatabase where data are saved
 	Database database.AppDatabase
+
+	// Blobs is where photos and attachments are saved
+	Blobs blobstore.BlobStore
+
+	// EditWindow is how long after sending a message its sender can edit it. Zero means no limit.
+	EditWindow time.Duration
+
+	// MaxImageSize and MaxFileSize are the maximum sizes, in bytes, of images and other files attached to messages.
+	// MaxImageSize applies to user and group photos too. Zero means the default (10 MB for images, 25 MB for other
+	// files).
+	MaxImageSize int64
+	MaxFileSize  int64
 }
 
 // Router is the package API interface representing an API handler builder
@@ -72,6 +88,21 @@ func New(cfg Config) (Router, error) {
 	if cfg.Database == nil {
 		return nil, errors.New("database is required")
 	}
+	if cfg.Blobs == nil {
+		return nil, errors.New("blob store is required")
+	}
+	if cfg.EditWindow < 0 {
+		return nil, errors.New("edit window must not be negative")
+	}
+	if cfg.MaxImageSize < 0 || cfg.MaxFileSize < 0 {
+		return nil, errors.New("attachment size limits must not be negative")
+	}
+	if cfg.MaxImageSize == 0 {
+		cfg.MaxImageSize = defaultMaxImageSize
+	}
+	if cfg.MaxFileSize == 0 {
+		cfg.MaxFileSize = defaultMaxFileSize
+	}
 
 	// Create a new router where we will register HTTP endpoints. The server will pass requests to this router to be
 	// handled.
@@ -79,11 +110,23 @@ func New(cfg Config) (Router, error) {
 	router.RedirectTrailingSlash = false
 	router.RedirectFixedPath = false
 
-	return &_router{
-		router:     router,
-		baseLogger: cfg.Logger,
-		db:         cfg.Database,
-	}, nil
+	rt := &_router{
+		router:       router,
+		baseLogger:   cfg.Logger,
+		db:           cfg.Database,
+		blobs:        cfg.Blobs,
+		events:       newEventHub(),
+		editWindow:   cfg.EditWindow,
+		maxImageSize: cfg.MaxImageSize,
+		maxFileSize:  cfg.MaxFileSize,
+		stop:         make(chan struct{}),
+	}
+
+	rt.background.Add(3)
+	go rt.collectBlobs()
+	go rt.expireMessages()
+	go rt.sendScheduledMessages()
+	return rt, nil
 }
 
 type _router struct {
@@ -94,4 +137,20 @@ type _router struct {
 	baseLogger logrus.FieldLogger
 
 	db database.AppDatabase
+
+	blobs blobstore.BlobStore
+
+	// events dispatches conversation changes to the clients connected to the event stream
+	events *eventHub
+
+	// editWindow is how long messages can be edited after being sent (0 for no limit)
+	editWindow time.Duration
+
+	// maxImageSize and maxFileSize limit the size of attachments (and photos, for maxImageSize)
+	maxImageSize int64
+	maxFileSize  int64
+
+	// stop is closed by Close, to end the background goroutines tracked by background
+	stop       chan struct{}
+	background sync.WaitGroup
 }
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+)
+
+const (
+	// defaultMaxImageSize and defaultMaxFileSize are the limits used when Config does not set them.
+	defaultMaxImageSize = 10 << 20
+	defaultMaxFileSize  = 25 << 20
+
+	// multipartOverhead is the room left for the other fields of a multipart message, on top of the attachment.
+	multipartOverhead = 1 << 20
+
+	// multipartMemory is the part of a multipart body kept in memory; the rest is spooled to temporary files.
+	multipartMemory = 1 << 20
+
+	// maxFileNameLength is the maximum length of the name of an attachment, in bytes.
+	maxFileNameLength = 255
+)
+
+// processedImageTypes are the MIME types (as sniffed by http.DetectContentType) of the images handled by the image
+// pipeline.
+var processedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// errTooLarge is returned when an upload exceeds the configured limits. replyError maps it to 413 Payload Too Large.
+var errTooLarge = errors.New("too large")
+
+// isMultipart reports whether the request body is a multipart form.
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// parseMultipartMessage limits the size of a multipart request body and parses it. The caller must remove the
+// temporary files with r.MultipartForm.RemoveAll.
+func (rt *_router) parseMultipartMessage(w http.ResponseWriter, r *http.Request) error {
+	limit := rt.maxFileSize
+	if rt.maxImageSize > limit {
+		limit = rt.maxImageSize
+	}
+	return parseMultipartForm(w, r, limit)
+}
+
+// parseMultipartForm parses a multipart request body of up to limit bytes of files (plus the multipart overhead).
+// Larger bodies are rejected with errTooLarge as soon as the limit is reached, without reading them in full.
+func parseMultipartForm(w http.ResponseWriter, r *http.Request, limit int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+
+	err := r.ParseMultipartForm(multipartMemory)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("request body over %d bytes: %w", tooLarge.Limit, errTooLarge)
+	} else if err != nil {
+		return fmt.Errorf("invalid multipart form: %w", errBadRequest)
+	}
+	return nil
+}
+
+// readAttachment reads an uploaded file, extracts its metadata and stores it. The MIME type is sniffed from the content,
+// as the one declared by the client can't be trusted; images are subject to the image size limit, other files to the
+// file size limit. Images of the formats handled by the image pipeline are stored without metadata, with thumbnails
+// (see storeImage); other images (e.g., BMP) are stored as they are, as any other file.
+func (rt *_router) readAttachment(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*database.Attachment, error) {
+	limit := rt.maxFileSize
+	if rt.maxImageSize > limit {
+		limit = rt.maxImageSize
+	}
+	if header.Size > limit {
+		return nil, fmt.Errorf("attachment over %d bytes: %w", limit, errTooLarge)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment: %w", err)
+	} else if len(data) == 0 {
+		return nil, fmt.Errorf("empty attachment: %w", errBadRequest)
+	}
+
+	a := &database.Attachment{
+		FileName: attachmentName(header.Filename),
+		MIMEType: http.DetectContentType(data),
+		Size:     int64(len(data)),
+	}
+
+	isImage := strings.HasPrefix(a.MIMEType, "image/")
+	if isImage && a.Size > rt.maxImageSize {
+		return nil, fmt.Errorf("image over %d bytes: %w", rt.maxImageSize, errTooLarge)
+	} else if !isImage && a.Size > rt.maxFileSize {
+		return nil, fmt.Errorf("file over %d bytes: %w", rt.maxFileSize, errTooLarge)
+	}
+
+	if processedImageTypes[a.MIMEType] {
+		img, key, err := rt.storeImage(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		a.MIMEType = img.MIMEType
+		a.Size = int64(len(img.Data))
+		a.Width, a.Height = img.Width, img.Height
+		a.Checksum = key
+		return a, nil
+	}
+
+	a.Checksum, err = rt.storeBlob(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// attachmentName returns the base name of an uploaded file, without directories and truncated to maxFileNameLength.
+func attachmentName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return "attachment"
+	}
+	for len(name) > maxFileNameLength {
+		// Drop whole runes, to keep the name valid UTF-8
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/blobstore"
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+)
+
+const (
+	// blobCollectInterval is how often blobs no longer referenced are removed from the store.
+	blobCollectInterval = 10 * time.Minute
+
+	// blobGracePeriod is how long an unreferenced blob is kept before being removed. It must be longer than any
+	// upload, as blobs are registered (without references) before being uploaded.
+	blobGracePeriod = time.Hour
+)
+
+// storeBlob registers the content in the database and uploads it to the blob store, returning its key. Identical
+// content is stored once.
+func (rt *_router) storeBlob(ctx context.Context, data []byte) (string, error) {
+	key := blobstore.Key(data)
+	if err := rt.db.RegisterBlob(ctx, key, int64(len(data))); err != nil {
+		return "", err
+	}
+	if err := rt.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// collectBlobs periodically removes from the store the blobs no longer referenced, until the router is closed.
+func (rt *_router) collectBlobs() {
+	defer rt.background.Done()
+
+	ticker := time.NewTicker(blobCollectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rt.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx := context.Background()
+		n, err := rt.db.CollectBlobs(ctx, globaltime.Now().Add(-blobGracePeriod), func(key string) error {
+			// A blob already gone from the store is collected all the same
+			if err := rt.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			rt.baseLogger.WithError(err).Warn("can't collect unreferenced blobs")
+		}
+		if n > 0 {
+			rt.baseLogger.WithField("count", n).Debug("unreferenced blobs collected")
+		}
+	}
+}
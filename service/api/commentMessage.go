@@ -4,45 +4,32 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) commentMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) commentMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
 	var request struct {
-		Type string `json:"type"`
+		Emoji string `json:"emoji"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Validate the request
-	if request.Type == "" {
-		http.Error(w, "Type is required", http.StatusBadRequest)
-		return
-	}
-
 	// Retrieve message ID from route parameters
 	messageID := ps.ByName("id")
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	// Add the reaction to the message
-	if err := rt.db.AddReaction(messageID, userID, request.Type); err != nil {
-		http.Error(w, "Failed to add reaction", http.StatusInternalServerError)
+	// Validate and add the reaction to the message, notifying the conversation members
+	reaction, err := rt.reactToMessage(r.Context(), ctx.UserID, messageID, request.Emoji)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to add reaction")
 		return
 	}
 
-	// Respond with success
+	// Respond with the reaction, with the emoji in its canonical form
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]bool{
-		"success": true,
-	})
+	json.NewEncoder(w).Encode(reaction)
 }
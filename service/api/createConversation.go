@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) createConversation(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	// Parse request body
+	var request struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the request
+	if request.UserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	} else if request.UserID == ctx.UserID {
+		http.Error(w, "Can't start a conversation with yourself", http.StatusBadRequest)
+		return
+	}
+
+	// Open the conversation, or retrieve the existing one
+	conversation, created, err := rt.db.GetOrCreateDirectConversation(r.Context(), ctx.UserID, request.UserID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to create conversation")
+		return
+	}
+
+	// Respond with the conversation
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(conversation)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) createGroupInvite(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Parse request body (optional: without limits, the invite never expires and can be used any number of times)
+	var request struct {
+		ExpiresAt *time.Time `json:"expiresAt"`
+		MaxUses   int        `json:"maxUses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the limits
+	var expiresAt time.Time
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+		if !expiresAt.After(globaltime.Now()) {
+			http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+			return
+		}
+	}
+	if request.MaxUses < 0 {
+		http.Error(w, "Maximum uses must be positive", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve group ID from route parameters
+	groupID := ps.ByName("id")
+
+	// Create the invite
+	invite, err := rt.db.CreateGroupInvite(r.Context(), ctx.UserID, groupID, expiresAt, request.MaxUses)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to create invite")
+		return
+	}
+
+	// Respond with the new invite
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
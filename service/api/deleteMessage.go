@@ -4,25 +4,29 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) deleteMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) deleteMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Retrieve message ID from route parameters
 	messageID := ps.ByName("id")
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+	// Find the conversation of the message, to notify its members
+	message, err := rt.db.GetMessage(r.Context(), ctx.UserID, messageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to delete message")
 		return
 	}
 
 	// Delete the message
-	if err := rt.db.DeleteMessage(messageID, userID); err != nil {
-		http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+	if err := rt.db.DeleteMessage(r.Context(), ctx.UserID, messageID); err != nil {
+		replyError(w, ctx, err, "Failed to delete message")
 		return
 	}
+	rt.notifyConversation(message.ConversationID, eventMessageDeleted, map[string]string{
+		"messageId": messageID,
+	})
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
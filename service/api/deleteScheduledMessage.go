@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) deleteScheduledMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve scheduled message ID from route parameters
+	scheduledID := ps.ByName("id")
+
+	// Cancel the scheduled message
+	if err := rt.db.DeleteScheduledMessage(r.Context(), ctx.UserID, scheduledID); err != nil {
+		replyError(w, ctx, err, "Failed to cancel scheduled message")
+		return
+	}
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) demoteGroupMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve group and member IDs from route parameters
+	groupID := ps.ByName("id")
+	memberID := ps.ByName("userId")
+
+	// Change the role of the member
+	role, err := rt.db.DemoteGroupMember(r.Context(), ctx.UserID, groupID, memberID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to demote member")
+		return
+	}
+	member := map[string]string{
+		"userId": memberID,
+		"role":   role,
+	}
+	rt.notifyConversation(groupID, eventMemberRole, member)
+
+	// Respond with the new role of the member
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(member)
+}
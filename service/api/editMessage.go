@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// messageEdit is the data of message.edited events. Unlike database.Message, which has the reactions and the star of
+// the user who fetched it, it is the same for all the members of the conversation.
+type messageEdit struct {
+	MessageID string             `json:"messageId"`
+	Content   string             `json:"content"`
+	EditedAt  *time.Time         `json:"editedAt"`
+	Mentions  []database.Mention `json:"mentions"`
+}
+
+func (rt *_router) editMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Parse request body
+	var request struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the request
+	if request.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve message ID from route parameters
+	messageID := ps.ByName("id")
+
+	// Replace the content, if the message is still within the edit window
+	message, err := rt.db.EditMessage(r.Context(), ctx.UserID, messageID, request.Content, rt.editWindow)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to edit message")
+		return
+	}
+	rt.notifyConversation(message.ConversationID, eventMessageEdited, messageEdit{
+		MessageID: message.ID,
+		Content:   message.Content,
+		EditedAt:  message.EditedAt,
+		Mentions:  message.Mentions,
+	})
+
+	// Respond with the updated message
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(message)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestRouter returns a router on a new, empty database, without the background tasks.
+func newTestRouter(t *testing.T) *_router {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "wasatext.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	db, err := database.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &_router{baseLogger: logger, db: db, events: newEventHub()}
+}
+
+// requestContext returns the context of a request authenticated as userID.
+func (rt *_router) requestContext(userID string) reqcontext.RequestContext {
+	return reqcontext.RequestContext{Logger: rt.baseLogger, UserID: userID}
+}
+
+func TestEditMessageEvent(t *testing.T) {
+	ctx := context.Background()
+	rt := newTestRouter(t)
+	alice, err := rt.db.GetOrCreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := rt.db.GetOrCreateUser(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := rt.db.GetOrCreateDirectConversation(ctx, alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rt.db.SaveMessage(ctx, alice.ID, database.NewMessage{ConversationID: c.ID, Content: "helo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.db.StarMessage(ctx, alice.ID, m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.db.AddReaction(ctx, alice.ID, m.ID, "👍"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, _ := rt.events.subscribe(bob.ID)
+	defer rt.events.unsubscribe(sub)
+	r := httptest.NewRequest(http.MethodPatch, "/messages/"+m.ID, strings.NewReader(`{"content": "hello"}`))
+	w := httptest.NewRecorder()
+	rt.editMessage(w, r, httprouter.Params{{Key: "id", Value: m.ID}}, rt.requestContext(alice.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("editMessage = %d %s", w.Code, w.Body.String())
+	}
+
+	// The editor gets their own view of the message
+	var reply database.Message
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil || !reply.Starred {
+		t.Errorf("reply = %+v, %v, want the message starred", reply, err)
+	}
+
+	// The other members get the new content, without the reactions and the star of the editor
+	events, _ := receive(sub)
+	if len(events) != 1 || events[0].Type != eventMessageEdited {
+		t.Fatalf("bob received %v, want a message.edited event", events)
+	}
+	data, err := json.Marshal(events[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["messageId"] != m.ID || payload["content"] != "hello" || payload["editedAt"] == nil {
+		t.Errorf("event data = %s, want the new content of %s", data, m.ID)
+	}
+	for _, field := range []string{"starred", "reactions"} {
+		if _, ok := payload[field]; ok {
+			t.Errorf("event data = %s, with the %s of the editor", data, field)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxEmojiRunes is the maximum number of code points in a reaction: enough for the longest ZWJ sequences (e.g.,
+// couples with skin tones).
+const maxEmojiRunes = 16
+
+// Code points with a special role in emoji sequences.
+const (
+	zeroWidthJoiner    = '\u200d'
+	variationSelector  = '\ufe0f' // emoji presentation
+	combiningKeycap    = '\u20e3'
+	blackFlag          = '\U0001f3f4' // base of the subdivision flags, followed by tags
+	cancelTag          = '\U000e007f'
+	regionalIndicatorA = '\U0001f1e6'
+	regionalIndicatorZ = '\U0001f1ff'
+)
+
+// emojiBases is the allowlist of the code points that can start an emoji (the pictographs of the Unicode emoji data,
+// besides the flags and keycaps, handled separately).
+var emojiBases = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00ae, Stride: 5},
+		{Lo: 0x203c, Hi: 0x2049, Stride: 13},
+		{Lo: 0x2122, Hi: 0x2139, Stride: 23},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21a9, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2328, Hi: 0x23cf, Stride: 167},
+		{Lo: 0x23e9, Hi: 0x23f3, Stride: 1},
+		{Lo: 0x23f8, Hi: 0x23fa, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25ab, Stride: 1},
+		{Lo: 0x25b6, Hi: 0x25c0, Stride: 10},
+		{Lo: 0x25fb, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b07, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b55, Stride: 5},
+		{Lo: 0x3030, Hi: 0x303d, Stride: 13},
+		{Lo: 0x3297, Hi: 0x3299, Stride: 2},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f004, Hi: 0x1f0cf, Stride: 203},
+		{Lo: 0x1f170, Hi: 0x1f171, Stride: 1},
+		{Lo: 0x1f17e, Hi: 0x1f17f, Stride: 1},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f201, Hi: 0x1f202, Stride: 1},
+		{Lo: 0x1f21a, Hi: 0x1f22f, Stride: 21},
+		{Lo: 0x1f232, Hi: 0x1f23a, Stride: 1},
+		{Lo: 0x1f250, Hi: 0x1f251, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f3fa, Stride: 1},
+		{Lo: 0x1f400, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1},
+		{Lo: 0x1f7f0, Hi: 0x1f7f0, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+	},
+	LatinOffset: 1,
+}
+
+// isSkinTone reports whether r is one of the Fitzpatrick modifiers, which follow a base to change its skin tone.
+func isSkinTone(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+// normalizeEmoji checks that s is a single emoji, and returns it in a canonical form, so that the same emoji typed
+// with or without the presentation selector counts as one reaction: the selector is kept only after the bases from
+// the older blocks (which default to the text presentation) not followed by a skin tone.
+//
+// Accepted emoji are flags (a pair of regional indicators), keycaps, and sequences of pictographs joined by ZWJ, each
+// with an optional skin tone, or a black flag followed by tags (subdivision flags).
+func normalizeEmoji(s string) (string, bool) {
+	runes := []rune(strings.ReplaceAll(s, string(variationSelector), ""))
+	if len(runes) == 0 || len(runes) > maxEmojiRunes {
+		return "", false
+	}
+
+	// Flags and keycaps
+	if len(runes) == 2 && isRegionalIndicator(runes[0]) && isRegionalIndicator(runes[1]) {
+		return string(runes), true
+	}
+	if len(runes) == 2 && runes[1] == combiningKeycap && strings.ContainsRune("0123456789#*", runes[0]) {
+		return string([]rune{runes[0], variationSelector, combiningKeycap}), true
+	}
+
+	var b strings.Builder
+	for i, element := range strings.Split(string(runes), string(zeroWidthJoiner)) {
+		elementRunes := []rune(element)
+		if len(elementRunes) == 0 {
+			return "", false
+		}
+		base, rest := elementRunes[0], elementRunes[1:]
+		if !unicode.Is(emojiBases, base) {
+			return "", false
+		}
+
+		if i > 0 {
+			b.WriteRune(zeroWidthJoiner)
+		}
+		b.WriteRune(base)
+		switch {
+		case len(rest) == 1 && isSkinTone(rest[0]):
+			b.WriteRune(rest[0])
+		case len(rest) > 1 && base == blackFlag && i == 0 && rest[len(rest)-1] == cancelTag:
+			for _, r := range rest[:len(rest)-1] {
+				if r < 0xe0020 || r > 0xe007e {
+					return "", false
+				}
+			}
+			b.WriteString(string(rest))
+		case len(rest) == 0:
+			if base < 0x1f000 {
+				b.WriteRune(variationSelector)
+			}
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
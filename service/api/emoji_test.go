@@ -0,0 +1,60 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEmoji(t *testing.T) {
+	const scotland = "🏴\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f"
+	tests := []struct {
+		emoji string
+		want  string
+	}{
+		{"👍", "👍"},
+		{"👍\ufe0f", "👍"}, // pictographs default to the emoji presentation
+		{"❤", "❤\ufe0f"}, // older symbols don't
+		{"❤\ufe0f", "❤\ufe0f"},
+		{"©", "©\ufe0f"},
+		{"👍🏽", "👍🏽"},
+		{"👍\ufe0f🏽", "👍🏽"},
+		{"👨\u200d👩\u200d👧\u200d👦", "👨\u200d👩\u200d👧\u200d👦"},
+		{"👩\u200d❤\u200d👨", "👩\u200d❤\ufe0f\u200d👨"},
+		{"👩🏻\u200d❤\ufe0f\u200d👨🏿", "👩🏻\u200d❤\ufe0f\u200d👨🏿"},
+		{"🇮🇹", "🇮🇹"},
+		{"1\u20e3", "1\ufe0f\u20e3"},
+		{"#\ufe0f\u20e3", "#\ufe0f\u20e3"},
+		{scotland, scotland},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeEmoji(tt.emoji)
+		if !ok || got != tt.want {
+			t.Errorf("normalizeEmoji(%+q) = %+q, %v, want %+q", tt.emoji, got, ok, tt.want)
+		}
+	}
+
+	for _, emoji := range []string{
+		"",
+		"\ufe0f",
+		"a",
+		"ok",
+		"👍👍",
+		"👍 ",
+		"👍🏽🏽",
+		"🏽", // a skin tone on its own
+		"👍\u200d",
+		"\u200d👍",
+		"👨\u200d\u200d👩",
+		"🇮",
+		"🇮🇹🇮",
+		"a\u20e3",
+		"🏴\U000e0067\U000e0062",            // tags without the cancel tag
+		"🏴\U000e0001\U000e007f",            // not a tag of a letter
+		"👍\U000e0067\U000e007f",            // tags on another base
+		strings.Repeat("👍\u200d", 8) + "👍", // 17 code points
+	} {
+		if got, ok := normalizeEmoji(emoji); ok {
+			t.Errorf("normalizeEmoji(%+q) = %+q, want an error", emoji, got)
+		}
+	}
+}
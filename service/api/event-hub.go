@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+)
+
+// Types of the events pushed to clients.
+const (
+	eventMessageCreated  = "message.created"
+	eventMessageEdited   = "message.edited"
+	eventMessageDeleted  = "message.deleted"
+	eventMessageExpired  = "message.expired"
+	eventMessagePinned   = "message.pinned"
+	eventMessageUnpinned = "message.unpinned"
+	eventReactionAdded   = "reaction.added"
+	eventReactionRemoved = "reaction.removed"
+	eventGroupRenamed    = "group.renamed"
+	eventMemberAdded     = "member.added"
+	eventMemberLeft      = "member.left"
+	eventMemberRemoved   = "member.removed"
+	eventMemberRole      = "member.role"
+
+	// A new message mentions the user, sent only to the members mentioned (the data is a database.Message)
+	eventMessageMentioned = "message.mentioned"
+
+	// The timer of disappearing messages of a conversation changed
+	eventConversationTimer = "conversation.timer"
+
+	// Receipts, sent when a member receives or reads new messages (the data is a database.Receipt)
+	eventConversationReceived = "conversation.received"
+	eventConversationRead     = "conversation.read"
+)
+
+// subscriptionBuffer is the number of events queued for a subscriber. A subscriber that falls behind by more than this
+// is disconnected: clients are expected to reconnect and refresh their state.
+const subscriptionBuffer = 64
+
+// Event is a change in a conversation, pushed to its members.
+type Event struct {
+	ID             uint64      `json:"id"`
+	Type           string      `json:"type"`
+	ConversationID string      `json:"conversationId"`
+	Timestamp      time.Time   `json:"timestamp"`
+	Data           interface{} `json:"data"`
+}
+
+// subscription receives the events addressed to a user. The events channel is closed when the subscription ends
+// (because of unsubscribe, a slow consumer, or the hub shutting down).
+type subscription struct {
+	userID string
+	events chan Event
+}
+
+// eventHub is an in-process publish/subscribe hub dispatching events to the connected users. A user may have any number
+// of subscriptions (e.g., one per device).
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*subscription]struct{}
+	lastID      uint64
+	closed      bool
+
+	// active counts the subscriptions not yet released with unsubscribe, so that close can wait for them
+	active sync.WaitGroup
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make(map[string]map[*subscription]struct{}),
+	}
+}
+
+// subscribe registers a new subscription for the user. It returns false if the hub has been closed.
+func (h *eventHub) subscribe(userID string) (*subscription, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, false
+	}
+
+	h.active.Add(1)
+	sub := &subscription{userID: userID, events: make(chan Event, subscriptionBuffer)}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*subscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	return sub, true
+}
+
+// unsubscribe ends the subscription, and releases it. It must be called exactly once for each subscription, when the
+// subscriber is done with it (even if the hub already closed the events channel).
+func (h *eventHub) unsubscribe(sub *subscription) {
+	h.mu.Lock()
+	h.remove(sub)
+	h.mu.Unlock()
+	h.active.Done()
+}
+
+// remove closes the events channel of the subscription, if still open. It must be called with h.mu held.
+func (h *eventHub) remove(sub *subscription) {
+	subs, ok := h.subscribers[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.userID)
+	}
+	close(sub.events)
+}
+
+// publish sends the event to every subscription of the given users. It never blocks: subscriptions whose buffer is full
+// are dropped.
+func (h *eventHub) publish(ev Event, userIDs []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.lastID++
+	ev.ID = h.lastID
+	ev.Timestamp = globaltime.Now().UTC()
+	for _, userID := range userIDs {
+		for sub := range h.subscribers[userID] {
+			select {
+			case sub.events <- ev:
+			default:
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+// close ends all subscriptions, and waits until subscribers release them. Events already queued are still delivered, as
+// subscribers drain their channel before noticing it is closed. Further subscriptions are refused.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+	h.mu.Unlock()
+	h.active.Wait()
+}
+
+// notifyConversation publishes an event to all members of a conversation, plus any non-member in `also` (e.g., a member
+// who just left). Failures are logged and otherwise ignored: the change has already been committed, and clients can
+// always catch up by refreshing. The lookup of the members is not bound to the request, which may be already over.
+func (rt *_router) notifyConversation(conversationID string, eventType string, data interface{}, also ...string) {
+	members, err := rt.db.GetConversationMembers(context.Background(), conversationID)
+	if err != nil {
+		rt.baseLogger.WithError(err).WithField("conversation", conversationID).Warn("can't notify conversation members")
+		return
+	}
+	rt.events.publish(Event{
+		Type:           eventType,
+		ConversationID: conversationID,
+		Data:           data,
+	}, append(members, also...))
+}
+
+// notifyMentions publishes a new message to the members it mentions, in addition to the event sent to all members, so
+// that clients can alert them even when the conversation is muted or not open.
+func (rt *_router) notifyMentions(message database.Message) {
+	var users []string
+	seen := map[string]bool{}
+	for _, m := range message.Mentions {
+		if !seen[m.UserID] {
+			seen[m.UserID] = true
+			users = append(users, m.UserID)
+		}
+	}
+	if len(users) == 0 {
+		return
+	}
+	rt.events.publish(Event{
+		Type:           eventMessageMentioned,
+		ConversationID: message.ConversationID,
+		Data:           message,
+	}, users)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// receive returns the events queued for a subscription, and whether its channel has been closed.
+func receive(sub *subscription) (events []Event, closed bool) {
+	for {
+		select {
+		case ev, ok := <-sub.events:
+			if !ok {
+				return events, true
+			}
+			events = append(events, ev)
+		default:
+			return events, false
+		}
+	}
+}
+
+func TestEventHubPublish(t *testing.T) {
+	h := newEventHub()
+	phone, _ := h.subscribe("alice")
+	laptop, _ := h.subscribe("alice")
+	other, _ := h.subscribe("bob")
+
+	h.publish(Event{Type: eventMessageCreated}, []string{"alice"})
+	h.publish(Event{Type: eventMessageEdited}, []string{"alice", "carol"})
+
+	for _, sub := range []*subscription{phone, laptop} {
+		events, closed := receive(sub)
+		if closed || len(events) != 2 {
+			t.Fatalf("alice received %v (closed: %v), want 2 events", events, closed)
+		}
+		if events[0].Type != eventMessageCreated || events[1].Type != eventMessageEdited {
+			t.Errorf("events out of order: %v", events)
+		}
+		if events[0].ID == 0 || events[1].ID <= events[0].ID {
+			t.Errorf("event IDs not increasing: %d, %d", events[0].ID, events[1].ID)
+		}
+	}
+	if events, _ := receive(other); len(events) != 0 {
+		t.Errorf("bob received %v, want nothing", events)
+	}
+
+	// Ending a subscription leaves the others of the user
+	h.unsubscribe(phone)
+	h.publish(Event{Type: eventMessageDeleted}, []string{"alice"})
+	if events, _ := receive(laptop); len(events) != 1 {
+		t.Errorf("laptop received %v, want 1 event", events)
+	}
+	h.unsubscribe(laptop)
+	h.unsubscribe(other)
+}
+
+func TestEventHubDropsSlowSubscriber(t *testing.T) {
+	h := newEventHub()
+	slow, _ := h.subscribe("alice")
+	fast, _ := h.subscribe("alice")
+
+	for i := 0; i < subscriptionBuffer; i++ {
+		h.publish(Event{Type: eventMessageCreated}, []string{"alice"})
+		receive(fast)
+	}
+	// The buffer of slow is full: the next event drops it, without blocking
+	h.publish(Event{Type: eventMessageCreated}, []string{"alice"})
+
+	events, closed := receive(slow)
+	if !closed {
+		t.Fatal("slow subscription not closed")
+	}
+	if len(events) != subscriptionBuffer {
+		t.Errorf("slow subscription received %d events before closing, want %d", len(events), subscriptionBuffer)
+	}
+	if events, closed := receive(fast); closed || len(events) != 1 {
+		t.Errorf("fast subscription received %d events (closed: %v), want 1", len(events), closed)
+	}
+
+	// The dropped subscription must still be released
+	h.unsubscribe(slow)
+	h.unsubscribe(fast)
+}
+
+func TestEventHubClose(t *testing.T) {
+	h := newEventHub()
+	sub, _ := h.subscribe("alice")
+	h.publish(Event{Type: eventMessageCreated}, []string{"alice"})
+
+	done := make(chan struct{})
+	go func() {
+		h.close()
+		close(done)
+	}()
+
+	// Queued events are still delivered, then the channel is closed
+	ev, ok := <-sub.events
+	if !ok || ev.Type != eventMessageCreated {
+		t.Fatalf("received %v (ok: %v), want the queued event", ev, ok)
+	}
+	if _, ok := <-sub.events; ok {
+		t.Fatal("events channel not closed")
+	}
+
+	// close waits for the subscription to be released
+	select {
+	case <-done:
+		t.Fatal("close returned before unsubscribe")
+	case <-time.After(50 * time.Millisecond):
+	}
+	h.unsubscribe(sub)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("close did not return after unsubscribe")
+	}
+
+	if _, ok := h.subscribe("alice"); ok {
+		t.Error("subscribe succeeded after close")
+	}
+	h.publish(Event{Type: eventMessageCreated}, []string{"alice"})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+)
+
+// expireInterval is how often expired messages are deleted, to free their storage. They are hidden as soon as they
+// expire.
+const expireInterval = time.Minute
+
+// expireMessages periodically deletes the messages whose timer ran out, notifying the members of their conversations,
+// until the router is closed.
+func (rt *_router) expireMessages() {
+	defer rt.background.Done()
+
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rt.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Messages are deleted in batches, until none is left (or the router is closed)
+		ctx := context.Background()
+		for {
+			expired, err := rt.db.DeleteExpiredMessages(ctx, globaltime.Now())
+			if err != nil {
+				rt.baseLogger.WithError(err).Warn("can't delete expired messages")
+				break
+			} else if len(expired) == 0 {
+				break
+			}
+			rt.baseLogger.WithField("count", len(expired)).Debug("expired messages deleted")
+			for _, m := range expired {
+				rt.notifyConversation(m.ConversationID, eventMessageExpired, map[string]string{
+					"messageId": m.ID,
+				})
+			}
+
+			select {
+			case <-rt.stop:
+				return
+			default:
+			}
+		}
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
 	var request struct {
 		ToConversationID string `json:"toConversationId"`
@@ -26,18 +27,13 @@ func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps htt
 	// Retrieve message ID from route parameters
 	messageID := ps.ByName("id")
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
 	// Forward the message
-	if err := rt.db.ForwardMessage(messageID, request.ToConversationID, userID); err != nil {
-		http.Error(w, "Failed to forward message", http.StatusInternalServerError)
+	message, err := rt.db.ForwardMessage(r.Context(), ctx.UserID, messageID, request.ToConversationID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to forward message")
 		return
 	}
+	rt.notifyConversation(message.ConversationID, eventMessageCreated, message)
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getAttachment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve attachment ID from route parameters
+	attachmentID := ps.ByName("id")
+
+	// Fetch the attachment, if the user can see its message
+	attachment, err := rt.db.GetAttachment(r.Context(), ctx.UserID, attachmentID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve attachment")
+		return
+	}
+
+	// Images can be served as thumbnails
+	key, mimeType, err := rt.imageVariant(r.Context(), r, attachment.Checksum, attachment.MIMEType)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve attachment")
+		return
+	}
+
+	// Open the content, stored as a blob
+	blob, err := rt.blobs.Get(r.Context(), key)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to read attachment")
+		return
+	}
+	defer blob.Close()
+
+	// Images are shown inline, other files are downloaded. As the content comes from users, browsers must neither
+	// guess another type nor run scripts in it.
+	disposition := "attachment"
+	if strings.HasPrefix(mimeType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+
+	// Blobs never change: their key is a strong validator
+	w.Header().Set("ETag", `"`+key+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+
+	// ServeContent handles conditional and range requests
+	http.ServeContent(w, r, attachment.FileName, time.Time{}, blob)
+}
@@ -4,20 +4,39 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) getConversation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) getConversation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Retrieve conversation ID from route parameters
 	conversationID := ps.ByName("id")
 
-	// Fetch messages from the database
-	messages, err := rt.db.GetMessages(conversationID)
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		replyError(w, ctx, err, "Invalid pagination parameters")
 		return
 	}
 
+	// Fetch a page of messages from the database
+	messages, more, err := rt.db.GetMessages(r.Context(), ctx.UserID, conversationID, page)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve messages")
+		return
+	}
+	if len(messages) > 0 {
+		oldest, newest := messages[0], messages[len(messages)-1]
+		setPageLinks(w, r, page,
+			database.Cursor{Timestamp: oldest.Timestamp, ID: oldest.ID},
+			database.Cursor{Timestamp: newest.Timestamp, ID: newest.ID}, more)
+
+		// The messages of the page are now received by the user
+		if err := rt.markReceived(r.Context(), ctx.UserID, conversationID, newest.Timestamp); err != nil {
+			ctx.Logger.WithError(err).Warn("can't record delivery")
+		}
+	}
+
 	// Respond with the list of messages
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// sseHeartbeat is how often a comment is sent on idle streams, to keep proxies from closing them and to detect
+	// disconnected clients.
+	sseHeartbeat = 25 * time.Second
+
+	// sseWriteTimeout is the deadline for writing a single event. The server-wide write timeout can't be used, as it
+	// would cut the stream.
+	sseWriteTimeout = 10 * time.Second
+)
+
+// getEvents streams the events of the conversations of the user as Server-Sent Events, until the client disconnects or
+// the server shuts down.
+func (rt *_router) getEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	rc := http.NewResponseController(w)
+
+	sub, ok := rt.events.subscribe(ctx.UserID)
+	if !ok {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer rt.events.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// write sends a chunk of the stream, failing if the client does not read it in time
+	write := func(chunk string) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, chunk); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Tell the client how long to wait before reconnecting, and send the headers right away
+	if err := write("retry: 3000\n\n"); err != nil {
+		ctx.Logger.WithError(err).Debug("event stream closed")
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var chunk string
+		var delivered *Event
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			chunk = ": heartbeat\n\n"
+		case ev, ok := <-sub.events:
+			if !ok {
+				// Closed by the hub: either the server is shutting down, or the client was too slow
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				ctx.Logger.WithError(err).Error("can't encode event")
+				continue
+			}
+			chunk = fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+			delivered = &ev
+		}
+
+		if err := write(chunk); err != nil {
+			ctx.Logger.WithError(err).Debug("event stream closed")
+			return
+		}
+		if delivered != nil {
+			rt.delivered(ctx.UserID, *delivered)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getGroupInvites(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve group ID from route parameters
+	groupID := ps.ByName("id")
+
+	// Fetch the invites from the database
+	invites, err := rt.db.GetGroupInvites(r.Context(), ctx.UserID, groupID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve invites")
+		return
+	}
+
+	// Respond with the list of invites
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
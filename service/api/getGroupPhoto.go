@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getGroupPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve group ID from route parameters
+	groupID := ps.ByName("id")
+
+	// Fetch the photo from the database, if the user is a member of the group
+	photo, err := rt.db.GetGroupPhoto(r.Context(), ctx.UserID, groupID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve photo")
+		return
+	}
+
+	// Respond with the image
+	rt.servePhoto(w, r, ctx, photo)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getMessageHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve message ID from route parameters
+	messageID := ps.ByName("id")
+
+	// Fetch the revisions from the database
+	revisions, err := rt.db.GetMessageHistory(r.Context(), ctx.UserID, messageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve message history")
+		return
+	}
+
+	// Respond with the list of revisions
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
@@ -4,23 +4,38 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) getMyConversations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+func (rt *_router) getMyConversations(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	page, err := parsePage(r)
+	if err != nil {
+		replyError(w, ctx, err, "Invalid pagination parameters")
 		return
 	}
 
-	// Fetch conversations from the database
-	conversations, err := rt.db.GetConversations(userID)
+	// Older pages list the conversations as they were when the first one was fetched (see database.Cursor). The
+	// snapshot is taken before the query: a conversation changing meanwhile may be repeated, but not skipped.
+	snapshot := globaltime.Now()
+	if page.Before != nil && !page.Before.Snapshot.IsZero() {
+		snapshot = page.Before.Snapshot
+	}
+
+	// Fetch a page of conversations from the database (most recently active first)
+	conversations, more, err := rt.db.GetConversations(r.Context(), ctx.UserID, page)
 	if err != nil {
-		http.Error(w, "Failed to retrieve conversations", http.StatusInternalServerError)
+		replyError(w, ctx, err, "Failed to retrieve conversations")
 		return
 	}
+	if len(conversations) > 0 {
+		newest, oldest := conversations[0], conversations[len(conversations)-1]
+		setPageLinks(w, r, page,
+			database.Cursor{Timestamp: oldest.Activity, ID: oldest.ID, Snapshot: snapshot},
+			database.Cursor{Timestamp: newest.Activity, ID: newest.ID}, more)
+	}
 
 	// Respond with the list of conversations
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getPinnedMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve conversation ID from route parameters
+	conversationID := ps.ByName("id")
+
+	// Fetch the pinned messages of the conversation
+	pins, err := rt.db.GetPinnedMessages(r.Context(), ctx.UserID, conversationID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve pinned messages")
+		return
+	}
+
+	// Respond with the pinned messages
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pins)
+}
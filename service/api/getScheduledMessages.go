@@ -0,0 +1,23 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getScheduledMessages(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	// Fetch the messages scheduled by the user
+	messages, err := rt.db.GetScheduledMessages(r.Context(), ctx.UserID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve scheduled messages")
+		return
+	}
+
+	// Respond with the scheduled messages
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(messages)
+}
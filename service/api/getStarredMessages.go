@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getStarredMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Stars are private: only the user's own can be listed
+	if id := ps.ByName("id"); id != "me" && id != ctx.UserID {
+		http.Error(w, "Starred messages are private", http.StatusForbidden)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		replyError(w, ctx, err, "Invalid pagination parameters")
+		return
+	}
+
+	// Fetch a page of the messages starred by the user, with cursors on the time they were starred
+	starred, more, err := rt.db.GetStarredMessages(r.Context(), ctx.UserID, page)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve starred messages")
+		return
+	}
+	if len(starred) > 0 {
+		newest, oldest := starred[0], starred[len(starred)-1]
+		setPageLinks(w, r, page,
+			database.Cursor{Timestamp: oldest.StarredAt, ID: oldest.Message.ID},
+			database.Cursor{Timestamp: newest.StarredAt, ID: newest.Message.ID}, more)
+	}
+
+	// Respond with the starred messages
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(starred)
+}
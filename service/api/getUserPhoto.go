@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) getUserPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve user ID from route parameters
+	userID := ps.ByName("id")
+
+	// Fetch the photo from the database
+	photo, err := rt.db.GetUserPhoto(r.Context(), userID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve photo")
+		return
+	}
+
+	// Respond with the image
+	rt.servePhoto(w, r, ctx, photo)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/PrinceLM1013/WasaText/service/imaging"
+)
+
+// photoMaxAge is how long clients can use a cached photo without checking whether it changed. Photos are served at
+// fixed URLs, so they can't be cached forever as attachments.
+const photoMaxAge = 5 * time.Minute
+
+// storeImage validates an uploaded image and stores it, without metadata, with its thumbnails. It returns the image as
+// stored, and its key. Invalid images are rejected with errBadRequest, images with too many pixels with errTooLarge.
+func (rt *_router) storeImage(ctx context.Context, data []byte) (*imaging.Image, string, error) {
+	img, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return nil, "", fmt.Errorf("%s: %w", err, errTooLarge)
+	} else if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", err, errBadRequest)
+	}
+
+	key, err := rt.storeBlob(ctx, img.Data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	thumbnails := make([]database.Thumbnail, 0, len(img.Thumbnails))
+	for _, t := range img.Thumbnails {
+		thumbnailKey, err := rt.storeBlob(ctx, t.Data)
+		if err != nil {
+			return nil, "", err
+		}
+		thumbnails = append(thumbnails, database.Thumbnail{
+			Size:     t.Size,
+			Key:      thumbnailKey,
+			MIMEType: t.MIMEType,
+			Width:    t.Width,
+			Height:   t.Height,
+		})
+	}
+	if err := rt.db.SaveThumbnails(ctx, key, thumbnails); err != nil {
+		return nil, "", err
+	}
+	return img, key, nil
+}
+
+// readPhoto reads the image uploaded as the `photo` field of a multipart form, which must be within the image size
+// limit.
+func (rt *_router) readPhoto(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if err := parseMultipartForm(w, r, rt.maxImageSize); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.MultipartForm.RemoveAll()
+	}()
+
+	file, header, err := r.FormFile("photo")
+	if err != nil {
+		return nil, fmt.Errorf("missing photo: %w", errBadRequest)
+	}
+	defer file.Close()
+	if header.Size > rt.maxImageSize {
+		return nil, fmt.Errorf("photo over %d bytes: %w", rt.maxImageSize, errTooLarge)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, rt.maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading photo: %w", err)
+	} else if int64(len(data)) > rt.maxImageSize {
+		return nil, fmt.Errorf("photo over %d bytes: %w", rt.maxImageSize, errTooLarge)
+	}
+	return data, nil
+}
+
+// imageVariant returns the blob to serve for an image stored as key: the thumbnail for the size requested with the
+// `size` query parameter (one of imaging.ThumbnailSizes), or the image itself if no size is requested or the image is
+// not larger than the size.
+func (rt *_router) imageVariant(ctx context.Context, r *http.Request, key string, mimeType string) (string, string, error) {
+	param := r.URL.Query().Get("size")
+	if param == "" {
+		return key, mimeType, nil
+	}
+
+	size, err := strconv.Atoi(param)
+	valid := false
+	for _, s := range imaging.ThumbnailSizes {
+		valid = valid || (err == nil && size == s)
+	}
+	if !valid {
+		return "", "", fmt.Errorf("size must be one of %v: %w", imaging.ThumbnailSizes, errBadRequest)
+	}
+
+	thumbnail, err := rt.db.GetThumbnail(ctx, key, size)
+	if errors.Is(err, database.ErrNotFound) {
+		return key, mimeType, nil
+	} else if err != nil {
+		return "", "", err
+	}
+	return thumbnail.Key, thumbnail.MIMEType, nil
+}
+
+// servePhoto streams the photo of a user or a group (or its thumbnail, see imageVariant). The key of the blob is a
+// strong ETag; ServeContent handles the conditional and range requests.
+func (rt *_router) servePhoto(w http.ResponseWriter, r *http.Request, ctx reqcontext.RequestContext, photo database.Photo) {
+	key, mimeType, err := rt.imageVariant(r.Context(), r, photo.Key, photo.MIMEType)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to retrieve photo")
+		return
+	}
+
+	blob, err := rt.blobs.Get(r.Context(), key)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to read photo")
+		return
+	}
+	defer blob.Close()
+
+	// The type of photos uploaded before the image pipeline is unknown: it's sniffed, and anything but an image is
+	// served as a download
+	if mimeType == "" {
+		mimeType, err = sniffImageType(blob)
+		if err != nil {
+			replyError(w, ctx, err, "Failed to read photo")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("ETag", `"`+key+`"`)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(photoMaxAge.Seconds())))
+	http.ServeContent(w, r, "", photo.UpdatedAt, blob)
+}
+
+// sniffImageType returns the MIME type of an image from its first bytes, or application/octet-stream if it's not an
+// image. The reader is rewound.
+func sniffImageType(r io.ReadSeeker) (string, error) {
+	var head [512]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	mimeType := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "application/octet-stream", nil
+	}
+	return mimeType, nil
+}
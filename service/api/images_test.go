@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/blobstore"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/sirupsen/logrus"
+)
+
+// multipartRequest returns a request uploading content as the file field, with the declared size of the body left to
+// the transfer (as with chunked requests).
+func multipartRequest(t *testing.T, field string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write(content)
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/users/me/photo", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.ContentLength = -1
+	return r
+}
+
+func TestReadPhoto(t *testing.T) {
+	const limit = 1024
+	rt := &_router{maxImageSize: limit}
+
+	tests := []struct {
+		name  string
+		field string
+		size  int
+		want  error
+	}{
+		{"within the limit", "photo", limit, nil},
+		{"over the limit", "photo", limit + 1, errTooLarge},
+		{"over the body limit", "photo", limit + multipartOverhead, errTooLarge},
+		{"missing", "file", 10, errBadRequest},
+	}
+	for _, tt := range tests {
+		content := bytes.Repeat([]byte{'x'}, tt.size)
+		data, err := rt.readPhoto(httptest.NewRecorder(), multipartRequest(t, tt.field, content))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: readPhoto = %v, want %v", tt.name, err, tt.want)
+		} else if err == nil && !bytes.Equal(data, content) {
+			t.Errorf("%s: readPhoto returned %d bytes, want %d", tt.name, len(data), len(content))
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/users/me/photo", bytes.NewReader([]byte(`{"photo": ""}`)))
+	r.Header.Set("Content-Type", "application/json")
+	if _, err := rt.readPhoto(httptest.NewRecorder(), r); !errors.Is(err, errBadRequest) {
+		t.Errorf("JSON body: readPhoto = %v, want errBadRequest", err)
+	}
+}
+
+func TestServePhoto(t *testing.T) {
+	store, err := blobstore.NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &_router{blobs: store}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := reqcontext.RequestContext{Logger: logger}
+
+	content := []byte("\x89PNG\r\n\x1a\n not really a PNG, but it looks like one")
+	key := blobstore.Key(content)
+	if err := store.Put(context.Background(), key, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	serve := func(photo database.Photo, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/users/abcdef012345/photo", nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		rt.servePhoto(w, r, ctx, photo)
+		return w
+	}
+
+	w := serve(database.Photo{Key: key, MIMEType: "image/png", UpdatedAt: updated}, nil)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("GET = %d %q", w.Code, w.Body.String())
+	}
+	for name, want := range map[string]string{
+		"Content-Type":           "image/png",
+		"ETag":                   `"` + key + `"`,
+		"Cache-Control":          "private, max-age=300",
+		"Last-Modified":          "Mon, 01 Jan 2024 12:00:00 GMT",
+		"X-Content-Type-Options": "nosniff",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Conditional and range requests
+	w = serve(database.Photo{Key: key, MIMEType: "image/png", UpdatedAt: updated}, http.Header{"If-None-Match": {`"` + key + `"`}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match = %d with %d bytes, want 304", w.Code, w.Body.Len())
+	}
+	w = serve(database.Photo{Key: key, MIMEType: "image/png", UpdatedAt: updated}, http.Header{"Range": {"bytes=0-7"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != string(content[:8]) {
+		t.Errorf("Range = %d %q, want 206 with the signature", w.Code, w.Body.String())
+	}
+
+	// Photos of unknown type are sniffed
+	w = serve(database.Photo{Key: key, UpdatedAt: updated}, nil)
+	if got := w.Header().Get("Content-Type"); got != "image/png" || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Errorf("sniffed photo = %s %q", got, w.Body.String())
+	}
+
+	// Unknown thumbnail size; missing blob, which is an inconsistency of the server
+	r := httptest.NewRequest(http.MethodGet, "/users/abcdef012345/photo?size=100", nil)
+	w = httptest.NewRecorder()
+	rt.servePhoto(w, r, ctx, database.Photo{Key: key, MIMEType: "image/png", UpdatedAt: updated})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("size=100: %d, want 400", w.Code)
+	}
+	w = serve(database.Photo{Key: blobstore.Key(nil), MIMEType: "image/png", UpdatedAt: updated}, nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("missing blob: %d, want 500", w.Code)
+	}
+}
+
+func TestSniffImageType(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"\xff\xd8\xff\xe0 JPEG", "image/jpeg"},
+		{"GIF89a", "image/gif"},
+		{"<html><script>alert(1)</script></html>", "application/octet-stream"},
+		{"", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		r := strings.NewReader(tt.content)
+		got, err := sniffImageType(r)
+		if err != nil || got != tt.want {
+			t.Errorf("sniffImageType(%q) = %s, %v, want %s", tt.content, got, err, tt.want)
+		}
+		if r.Len() != len(tt.content) {
+			t.Errorf("sniffImageType(%q) did not rewind", tt.content)
+		}
+	}
+}
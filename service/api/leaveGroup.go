@@ -4,25 +4,33 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) leaveGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) leaveGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Retrieve group ID from route parameters
 	groupID := ps.ByName("id")
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
 	// Remove the user from the group
-	if err := rt.db.LeaveGroup(groupID, userID); err != nil {
-		http.Error(w, "Failed to leave group", http.StatusInternalServerError)
+	newOwnerID, message, err := rt.db.LeaveGroup(r.Context(), ctx.UserID, groupID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to leave group")
 		return
 	}
+	rt.notifyConversation(groupID, eventMemberLeft, map[string]string{
+		"userId": ctx.UserID,
+	}, ctx.UserID)
+	if message.ID != "" {
+		rt.notifyConversation(groupID, eventMessageCreated, message)
+	}
+	if newOwnerID != "" {
+		rt.notifyConversation(groupID, eventMemberRole, map[string]string{
+			"userId": newOwnerID,
+			"role":   database.RoleOwner,
+		})
+	}
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) markConversationRead(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Parse request body: it's optional, without a message everything is marked as read
+	var request struct {
+		MessageID string `json:"messageId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve conversation ID from route parameters
+	conversationID := ps.ByName("id")
+
+	// Move the read receipt of the user forward
+	receipt, err := rt.markRead(r.Context(), ctx.UserID, conversationID, request.MessageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to mark conversation as read")
+		return
+	}
+
+	// Respond with the receipt
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
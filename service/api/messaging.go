@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+)
+
+// errBadRequest is returned by the functions in this file when the input is not valid. replyError maps it to
+// 400 Bad Request.
+var errBadRequest = errors.New("bad request")
+
+// The functions below implement the operations shared by the REST handlers and the WebSocket gateway, so that both
+// transports validate, store and notify in the same way.
+
+// postMessage sends a message to a conversation on behalf of userID, and notifies the members of the conversation.
+// The message must have a content, an attachment, or both.
+func (rt *_router) postMessage(ctx context.Context, userID string, m database.NewMessage) (database.Message, error) {
+	if err := checkNewMessage(m); err != nil {
+		return database.Message{}, err
+	}
+
+	message, err := rt.db.SaveMessage(ctx, userID, m)
+	if err != nil {
+		return message, err
+	}
+	rt.notifyConversation(message.ConversationID, eventMessageCreated, message)
+	rt.notifyMentions(message)
+	return message, nil
+}
+
+// checkNewMessage validates a message to be sent: it must have a content, an attachment, or both.
+func checkNewMessage(m database.NewMessage) error {
+	if m.ConversationID == "" {
+		return fmt.Errorf("conversation ID is required: %w", errBadRequest)
+	} else if m.Content == "" && m.Attachment == nil {
+		return fmt.Errorf("content or attachment is required: %w", errBadRequest)
+	}
+	return nil
+}
+
+// reactToMessage adds a reaction of userID to a message with an emoji, and notifies the members of the conversation.
+func (rt *_router) reactToMessage(ctx context.Context, userID string, messageID string, emoji string) (database.Reaction, error) {
+	if emoji == "" {
+		return database.Reaction{}, fmt.Errorf("emoji is required: %w", errBadRequest)
+	}
+	emoji, ok := normalizeEmoji(emoji)
+	if !ok {
+		return database.Reaction{}, fmt.Errorf("not a single emoji: %w", errBadRequest)
+	}
+
+	// Find the conversation of the message, to notify its members
+	message, err := rt.db.GetMessage(ctx, userID, messageID)
+	if err != nil {
+		return database.Reaction{}, err
+	}
+
+	reaction, err := rt.db.AddReaction(ctx, userID, messageID, emoji)
+	if err != nil {
+		return reaction, err
+	}
+	rt.notifyConversation(message.ConversationID, eventReactionAdded, reaction)
+	return reaction, nil
+}
+
+// markReceived records that userID received the messages of a conversation sent up to upTo, and notifies the members
+// if the receipt moved forward.
+func (rt *_router) markReceived(ctx context.Context, userID string, conversationID string, upTo time.Time) error {
+	receipt, changed, err := rt.db.MarkReceived(ctx, userID, conversationID, upTo)
+	if err != nil {
+		return err
+	}
+	if changed {
+		rt.notifyConversation(conversationID, eventConversationReceived, receipt)
+	}
+	return nil
+}
+
+// markRead records that userID read the messages of a conversation up to messageID (all of them if empty), and
+// notifies the members if the receipt moved forward.
+func (rt *_router) markRead(ctx context.Context, userID string, conversationID string, messageID string) (database.Receipt, error) {
+	if conversationID == "" {
+		return database.Receipt{}, fmt.Errorf("conversation ID is required: %w", errBadRequest)
+	}
+
+	receipt, changed, err := rt.db.MarkRead(ctx, userID, conversationID, messageID)
+	if err != nil {
+		return receipt, err
+	}
+	if changed {
+		rt.notifyConversation(conversationID, eventConversationRead, receipt)
+	}
+	return receipt, nil
+}
+
+// delivered is called once an event has been written to a client of userID. New messages from other users are then
+// received by the user.
+func (rt *_router) delivered(userID string, ev Event) {
+	message, ok := ev.Data.(database.Message)
+	if ev.Type != eventMessageCreated || !ok || message.SenderID == userID {
+		return
+	}
+	if err := rt.markReceived(context.Background(), userID, message.ConversationID, message.Timestamp); err != nil {
+		rt.baseLogger.WithError(err).WithField("message", message.ID).Warn("can't record delivery")
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+)
+
+// Cursors are opaque to clients: they are the base64url encoding of `<unix milliseconds>:<id>`, followed by
+// `:<unix milliseconds>` for cursors with a snapshot, so that the format can change without breaking them.
+
+func encodeCursor(c database.Cursor) string {
+	s := strconv.FormatInt(c.Timestamp.UnixMilli(), 10) + ":" + c.ID
+	if !c.Snapshot.IsZero() {
+		s += ":" + strconv.FormatInt(c.Snapshot.UnixMilli(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeCursor(s string) (*database.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	ms, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	c := &database.Cursor{Timestamp: time.UnixMilli(ms).UTC(), ID: parts[1]}
+	if len(parts) == 3 {
+		ms, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+		}
+		c.Snapshot = time.UnixMilli(ms).UTC()
+	}
+	return c, nil
+}
+
+// Search cursors are encoded the same way, with the rank of the result in place of the timestamp.
+
+func encodeSearchCursor(c database.SearchCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatFloat(c.Rank, 'g', -1, 64) + ":" + c.ID))
+}
+
+func decodeSearchCursor(s string) (*database.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", errBadRequest)
+	}
+	return &database.SearchCursor{Rank: rank, ID: parts[1]}, nil
+}
+
+// parsePage reads the `before`, `after` and `limit` query parameters.
+func parsePage(r *http.Request) (database.Page, error) {
+	var page database.Page
+	var err error
+	query := r.URL.Query()
+
+	if s := query.Get("before"); s != "" {
+		if page.Before, err = decodeCursor(s); err != nil {
+			return page, err
+		}
+	}
+	if s := query.Get("after"); s != "" {
+		if page.After, err = decodeCursor(s); err != nil {
+			return page, err
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		page.Limit, err = strconv.Atoi(s)
+		if err != nil || page.Limit < 1 || page.Limit > database.MaxPageSize {
+			return page, fmt.Errorf("limit must be between 1 and %d: %w", database.MaxPageSize, errBadRequest)
+		}
+	}
+	return page, nil
+}
+
+// setPageLinks sets the `Link` header (RFC 8288) of a non-empty paginated reply. oldest and newest are the edges of the
+// returned page, and more tells whether there are other items past the page in its direction.
+//
+// The "next" link continues in the direction of the page (older items by default, newer ones with `after`), and it is
+// omitted when there is nothing more. The "prev" link goes the other way from the page: for the default direction, it
+// is how clients poll for items newer than the ones they have.
+func setPageLinks(w http.ResponseWriter, r *http.Request, page database.Page, oldest database.Cursor, newest database.Cursor, more bool) {
+	link := func(rel string, param string, c database.Cursor, clear string) string {
+		query := r.URL.Query()
+		query.Del(clear)
+		query.Set(param, encodeCursor(c))
+		return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="` + rel + `"`
+	}
+
+	var links []string
+	if page.Forward() {
+		if more {
+			links = append(links, link("next", "after", newest, ""))
+		}
+		links = append(links, link("prev", "before", oldest, "after"))
+	} else {
+		if more {
+			links = append(links, link("next", "before", oldest, ""))
+		}
+		links = append(links, link("prev", "after", newest, "before"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
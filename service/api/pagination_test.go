@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	for _, c := range []database.Cursor{
+		{Timestamp: at, ID: "abc_DEF-123"},
+		{Timestamp: at, ID: "abc", Snapshot: at.Add(time.Hour)},
+		{Timestamp: time.UnixMilli(0).UTC(), ID: "x"},
+	} {
+		s := encodeCursor(c)
+		got, err := decodeCursor(s)
+		if err != nil {
+			t.Fatalf("decodeCursor(%q): %v", s, err)
+		}
+		if !got.Timestamp.Equal(c.Timestamp) || got.ID != c.ID || !got.Snapshot.Equal(c.Snapshot) {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", c, *got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	for _, s := range []string{
+		"",
+		"not base64!",
+		encode("1700000000000"),
+		encode("1700000000000:"),
+		encode("yesterday:abc"),
+		encode("1700000000000:abc:"),
+		encode("1700000000000:abc:later"),
+		encode("1700000000000:abc:0"),
+		encode("1700000000000:abc:1:2"),
+	} {
+		if _, err := decodeCursor(s); !errors.Is(err, errBadRequest) {
+			t.Errorf("decodeCursor(%q) = %v, want errBadRequest", s, err)
+		}
+	}
+}
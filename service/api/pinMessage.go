@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) pinMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve message ID from route parameters
+	messageID := ps.ByName("id")
+
+	// Pin the message in its conversation
+	message, changed, err := rt.db.PinMessage(r.Context(), ctx.UserID, messageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to pin message")
+		return
+	}
+	if changed {
+		rt.notifyConversation(message.ConversationID, eventMessagePinned, map[string]string{
+			"messageId": messageID,
+			"userId":    ctx.UserID,
+		})
+	}
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
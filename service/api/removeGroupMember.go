@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) removeGroupMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve group and member IDs from route parameters
+	groupID := ps.ByName("id")
+	memberID := ps.ByName("userId")
+
+	// Remove the member from the group
+	message, err := rt.db.RemoveGroupMember(r.Context(), ctx.UserID, groupID, memberID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to remove member")
+		return
+	}
+	rt.notifyConversation(groupID, eventMemberRemoved, map[string]string{
+		"userId":    memberID,
+		"removedBy": ctx.UserID,
+	}, memberID)
+	rt.notifyConversation(groupID, eventMessageCreated, message)
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
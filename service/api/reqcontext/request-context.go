@@ -7,6 +7,8 @@ Each value here should be assumed valid only per request only, with some excepti
 package reqcontext
 
 import (
+	"context"
+
 	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -18,4 +20,22 @@ type RequestContext struct {
 
 	// Logger is a custom field logger for the request
 	Logger logrus.FieldLogger
+
+	// UserID is the identifier of the authenticated user. It is empty for public routes.
+	UserID string
+}
+
+// contextKey is the type of the key used to store a RequestContext in a context.Context. Being unexported, no other
+// package can collide with it.
+type contextKey struct{}
+
+// NewContext returns a copy of parent carrying rc.
+func NewContext(parent context.Context, rc RequestContext) context.Context {
+	return context.WithValue(parent, contextKey{}, rc)
+}
+
+// FromContext returns the RequestContext stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (RequestContext, bool) {
+	rc, ok := ctx.Value(contextKey{}).(RequestContext)
+	return rc, ok
 }
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) revokeGroupInvite(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve group ID and invite token from route parameters
+	groupID := ps.ByName("id")
+	token := ps.ByName("token")
+
+	// Revoke the invite
+	if err := rt.db.RevokeGroupInvite(r.Context(), ctx.UserID, groupID, token); err != nil {
+		replyError(w, ctx, err, "Failed to revoke invite")
+		return
+	}
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+)
+
+const (
+	// scheduleInterval is how often the scheduled messages due are sent.
+	scheduleInterval = 5 * time.Second
+
+	// maxScheduleAhead is how far in the future messages can be scheduled.
+	maxScheduleAhead = 365 * 24 * time.Hour
+)
+
+// sendScheduledMessages periodically sends the scheduled messages due, notifying the members of their conversations,
+// until the router is closed. Scheduled messages are stored in the database, so the ones due while the server was down
+// are sent as soon as it starts again.
+func (rt *_router) sendScheduledMessages() {
+	defer rt.background.Done()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		// Messages are sent in batches, until none is due (or the router is closed)
+		ctx := context.Background()
+		for {
+			sent, discarded, err := rt.db.SendScheduledMessages(ctx, globaltime.Now())
+			for _, m := range sent {
+				rt.notifyConversation(m.ConversationID, eventMessageCreated, m)
+				rt.notifyMentions(m)
+			}
+			if discarded > 0 {
+				rt.baseLogger.WithField("count", discarded).Info("scheduled messages of former members discarded")
+			}
+			if err != nil {
+				rt.baseLogger.WithError(err).Warn("can't send scheduled messages")
+			}
+			if len(sent)+discarded == 0 {
+				break
+			}
+
+			select {
+			case <-rt.stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-rt.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
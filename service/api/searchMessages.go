@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) searchMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	search, err := parseSearch(r)
+	if err != nil {
+		replyError(w, ctx, err, "Invalid search parameters")
+		return
+	}
+
+	// Search the messages of the conversations of the user
+	results, more, err := rt.db.SearchMessages(r.Context(), ctx.UserID, search)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to search messages")
+		return
+	}
+	if more {
+		last := results[len(results)-1]
+		query := r.URL.Query()
+		query.Set("cursor", encodeSearchCursor(database.SearchCursor{Rank: last.Rank, ID: last.Message.ID}))
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+query.Encode()+`>; rel="next"`)
+	}
+
+	// Respond with the results, best matches first
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseSearch reads the query parameters of a search. Unlike the other lists, `before` and `after` are times (the
+// results are sorted by relevance, not by time), and pages are selected by `cursor`.
+func parseSearch(r *http.Request) (database.Search, error) {
+	var search database.Search
+	var err error
+	query := r.URL.Query()
+
+	search.Text = strings.TrimSpace(query.Get("q"))
+	if search.Text == "" {
+		return search, fmt.Errorf("q is required: %w", errBadRequest)
+	}
+	search.ConversationID = query.Get("conversation")
+	search.SenderID = query.Get("from")
+
+	if s := query.Get("before"); s != "" {
+		if search.Before, err = time.Parse(time.RFC3339, s); err != nil {
+			return search, fmt.Errorf("before must be a RFC 3339 date-time: %w", errBadRequest)
+		}
+	}
+	if s := query.Get("after"); s != "" {
+		if search.After, err = time.Parse(time.RFC3339, s); err != nil {
+			return search, fmt.Errorf("after must be a RFC 3339 date-time: %w", errBadRequest)
+		}
+	}
+	if s := query.Get("cursor"); s != "" {
+		if search.Cursor, err = decodeSearchCursor(s); err != nil {
+			return search, err
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		search.Limit, err = strconv.Atoi(s)
+		if err != nil || search.Limit < 1 || search.Limit > database.MaxPageSize {
+			return search, fmt.Errorf("limit must be between 1 and %d: %w", database.MaxPageSize, errBadRequest)
+		}
+	}
+	return search, nil
+}
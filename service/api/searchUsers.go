@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// userPrefixPattern matches the beginning of a username (see the pattern of usernames in doc/api.yaml).
+var userPrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,16}$`)
+
+// directoryEntry is a user in the results of the directory search.
+type directoryEntry struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Photo string `json:"photo,omitempty"`
+}
+
+func (rt *_router) searchUsers(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	query := r.URL.Query()
+	prefix := query.Get("q")
+	if !userPrefixPattern.MatchString(prefix) {
+		http.Error(w, "q must be the beginning of a username", http.StatusBadRequest)
+		return
+	}
+
+	// The cursor is the name of the last user of the previous page
+	var after string
+	if s := query.Get("cursor"); s != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(raw) == 0 {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		after = string(raw)
+	}
+	var limit int
+	if s := query.Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > database.MaxPageSize {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", database.MaxPageSize), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Search the users in the database
+	users, more, err := rt.db.SearchUsers(r.Context(), ctx.UserID, prefix, after, limit)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to search users")
+		return
+	}
+	if more {
+		query.Set("cursor", base64.RawURLEncoding.EncodeToString([]byte(users[len(users)-1].Name)))
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+query.Encode()+`>; rel="next"`)
+	}
+
+	entries := make([]directoryEntry, len(users))
+	for i, u := range users {
+		entries[i] = directoryEntry{ID: u.ID, Name: u.Name}
+		if u.HasPhoto {
+			entries[i].Photo = "/users/" + u.ID + "/photo"
+		}
+	}
+
+	// Respond with the list of users
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
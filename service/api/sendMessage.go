@@ -3,45 +3,107 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) sendMessage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Parse request body
-	var request struct {
-		ConversationID string `json:"conversationId"`
-		Content        string `json:"content"`
+func (rt *_router) sendMessage(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	var request database.NewMessage
+	var sendAt time.Time
+	if isMultipart(r) {
+		// Parse the multipart form, with the same fields as the JSON body plus an optional file
+		if err := rt.parseMultipartMessage(w, r); err != nil {
+			replyError(w, ctx, err, "Unable to parse form")
+			return
+		}
+		defer func() {
+			_ = r.MultipartForm.RemoveAll()
+		}()
+
+		request.ConversationID = r.FormValue("conversationId")
+		request.Content = r.FormValue("content")
+		request.ReplyTo = r.FormValue("replyTo")
+		if s := r.FormValue("sendAt"); s != "" {
+			var err error
+			if sendAt, err = time.Parse(time.RFC3339, s); err != nil {
+				http.Error(w, "sendAt must be a RFC 3339 date-time", http.StatusBadRequest)
+				return
+			}
+		}
+
+		file, header, err := r.FormFile("file")
+		if err == nil {
+			defer file.Close()
+			request.Attachment, err = rt.readAttachment(r.Context(), file, header)
+			if err != nil {
+				replyError(w, ctx, err, "Invalid attachment")
+				return
+			}
+		} else if err != http.ErrMissingFile {
+			http.Error(w, "Unable to read file", http.StatusBadRequest)
+			return
+		}
+	} else {
+		// Parse request body
+		var body struct {
+			ConversationID string     `json:"conversationId"`
+			Content        string     `json:"content"`
+			ReplyTo        string     `json:"replyTo"`
+			SendAt         *time.Time `json:"sendAt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		request = database.NewMessage{ConversationID: body.ConversationID, Content: body.Content, ReplyTo: body.ReplyTo}
+		if body.SendAt != nil {
+			sendAt = *body.SendAt
+		}
 	}
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+
+	// Messages to be sent later are scheduled, the others are sent right away
+	if sendAt.After(globaltime.Now()) {
+		rt.scheduleMessage(w, r, ctx, request, sendAt)
 		return
 	}
 
-	// Validate the request
-	if request.ConversationID == "" || request.Content == "" {
-		http.Error(w, "Conversation ID and content are required", http.StatusBadRequest)
+	// Validate and save the message, notifying the conversation members
+	message, err := rt.postMessage(r.Context(), ctx.UserID, request)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to send message")
 		return
 	}
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"messageID": message.ID,
+	})
+}
+
+// scheduleMessage stores a message to be sent at sendAt by the scheduler, responding with the scheduled message.
+func (rt *_router) scheduleMessage(w http.ResponseWriter, r *http.Request, ctx reqcontext.RequestContext, m database.NewMessage, sendAt time.Time) {
+	if err := checkNewMessage(m); err != nil {
+		replyError(w, ctx, err, "Failed to schedule message")
+		return
+	} else if sendAt.After(globaltime.Now().Add(maxScheduleAhead)) {
+		http.Error(w, "sendAt must be within a year", http.StatusBadRequest)
 		return
 	}
 
-	// Save the message to the database
-	messageID, err := rt.db.SaveMessage(request.ConversationID, userID, request.Content)
+	scheduled, err := rt.db.ScheduleMessage(r.Context(), ctx.UserID, m, sendAt)
 	if err != nil {
-		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		replyError(w, ctx, err, "Failed to schedule message")
 		return
 	}
 
-	// Respond with success
+	// Respond with the scheduled message
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
-		"messageID": messageID,
-	})
+	json.NewEncoder(w).Encode(scheduled)
 }
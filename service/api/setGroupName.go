@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) setGroupName(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (rt *_router) setGroupName(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
 	var request struct {
 		Name string `json:"name"`
@@ -27,10 +28,22 @@ func (rt *_router) setGroupName(w http.ResponseWriter, r *http.Request, ps httpr
 	groupID := ps.ByName("id")
 
 	// Update the group name
-	if err := rt.db.UpdateGroupName(groupID, request.Name); err != nil {
-		http.Error(w, "Failed to update group name", http.StatusInternalServerError)
+	message, err := rt.db.UpdateGroupName(r.Context(), ctx.UserID, groupID, request.Name)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to update group name")
 		return
 	}
+	if message.ID != "" {
+		rt.notifyConversation(groupID, eventGroupRenamed, map[string]string{
+			"name": request.Name,
+		})
+		rt.notifyConversation(groupID, eventMessageCreated, message)
+	}
 
 	// Respond with success
-	w.Header().Set
\ No newline at end of file
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
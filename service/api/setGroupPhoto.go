@@ -4,32 +4,33 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) setGroupPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Parse the multipart form
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
-		http.Error(w, "Unable to parse form", http.StatusBadRequest)
-		return
-	}
-
-	// Retrieve the photo file
-	file, _, err := r.FormFile("photo")
+func (rt *_router) setGroupPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Read the photo, within the image size limit
+	photo, err := rt.readPhoto(w, r)
 	if err != nil {
-		http.Error(w, "Photo is required", http.StatusBadRequest)
+		replyError(w, ctx, err, "Invalid photo")
 		return
 	}
-	defer file.Close()
 
 	// Retrieve group ID from route parameters
 	groupID := ps.ByName("id")
 
-	// Save the photo (e.g., to a file storage service or database)
-	if err := rt.db.SaveGroupPhoto(groupID, file); err != nil {
-		http.Error(w, "Failed to save group photo", http.StatusInternalServerError)
+	// Save the photo
+	img, key, err := rt.storeImage(r.Context(), photo)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to save group photo")
+		return
+	}
+	message, err := rt.db.SaveGroupPhoto(r.Context(), ctx.UserID, groupID, key, img.MIMEType)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to save group photo")
 		return
 	}
+	rt.notifyConversation(groupID, eventMessageCreated, message)
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) setMessageTimer(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Parse request body
+	var request struct {
+		Timer string `json:"timer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the request
+	if !database.ValidTimer(request.Timer) {
+		http.Error(w, "Timer must be one of off, 1h, 24h, 7d", http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve conversation ID from route parameters
+	conversationID := ps.ByName("id")
+
+	// Set the timer of the conversation
+	message, err := rt.db.SetMessageTimer(r.Context(), ctx.UserID, conversationID, request.Timer)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to set message timer")
+		return
+	}
+	if message.ID != "" {
+		rt.notifyConversation(conversationID, eventConversationTimer, map[string]string{
+			"messageTimer": request.Timer,
+		})
+		rt.notifyConversation(conversationID, eventMessageCreated, message)
+	}
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
@@ -4,34 +4,26 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) setMyPhoto(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Parse the multipart form
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
-		http.Error(w, "Unable to parse form", http.StatusBadRequest)
-		return
-	}
-
-	// Retrieve the photo file
-	file, _, err := r.FormFile("photo")
+func (rt *_router) setMyPhoto(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	// Read the photo, within the image size limit
+	photo, err := rt.readPhoto(w, r)
 	if err != nil {
-		http.Error(w, "Photo is required", http.StatusBadRequest)
+		replyError(w, ctx, err, "Invalid photo")
 		return
 	}
-	defer file.Close()
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+	// Save the photo
+	img, key, err := rt.storeImage(r.Context(), photo)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to save photo")
 		return
 	}
-
-	// Save the photo (e.g., to a file storage service or database)
-	if err := rt.db.SaveUserPhoto(userID, file); err != nil {
-		http.Error(w, "Failed to save photo", http.StatusInternalServerError)
+	if err := rt.db.SaveUserPhoto(r.Context(), ctx.UserID, key, img.MIMEType); err != nil {
+		replyError(w, ctx, err, "Failed to save photo")
 		return
 	}
 
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) setMyUserName(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func (rt *_router) setMyUserName(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
 	// Parse request body
 	var request struct {
 		Name string `json:"name"`
@@ -23,16 +24,9 @@ func (rt *_router) setMyUserName(w http.ResponseWriter, r *http.Request, _ httpr
 		return
 	}
 
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
-	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
 	// Update the username in the database
-	if err := rt.db.UpdateUserName(userID, request.Name); err != nil {
-		http.Error(w, "Failed to update username", http.StatusInternalServerError)
+	if err := rt.db.UpdateUserName(r.Context(), ctx.UserID, request.Name); err != nil {
+		replyError(w, ctx, err, "Failed to update username")
 		return
 	}
 
@@ -2,5 +2,11 @@ package api
 
 // Close should close everything opened in the lifecycle of the `_router`; for example, background goroutines.
 func (rt *_router) Close() error {
+	// End the event streams and WebSocket connections, waiting for them to be closed cleanly: the HTTP server shutdown
+	// would otherwise wait for the streams, and it does not track WebSocket connections at all
+	rt.events.close()
+
+	close(rt.stop)
+	rt.background.Wait()
 	return nil
 }
@@ -4,25 +4,36 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (rt *_router) uncommentMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Retrieve message ID from route parameters
+func (rt *_router) uncommentMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve message ID and emoji from route parameters
 	messageID := ps.ByName("id")
-
-	// Retrieve user ID from context (set by middleware)
-	userID, ok := r.Context().Value("userID").(string)
+	emoji, ok := normalizeEmoji(ps.ByName("emoji"))
 	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		http.Error(w, "Invalid emoji", http.StatusBadRequest)
+		return
+	}
+
+	// Find the conversation of the message, to notify its members
+	message, err := rt.db.GetMessage(r.Context(), ctx.UserID, messageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to remove reaction")
 		return
 	}
 
 	// Remove the reaction from the message
-	if err := rt.db.RemoveReaction(messageID, userID); err != nil {
-		http.Error(w, "Failed to remove reaction", http.StatusInternalServerError)
+	if err := rt.db.RemoveReaction(r.Context(), ctx.UserID, messageID, emoji); err != nil {
+		replyError(w, ctx, err, "Failed to remove reaction")
 		return
 	}
+	rt.notifyConversation(message.ConversationID, eventReactionRemoved, map[string]string{
+		"messageId": messageID,
+		"userId":    ctx.UserID,
+		"emoji":     emoji,
+	})
 
 	// Respond with success
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (rt *_router) unpinMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	// Retrieve message ID from route parameters
+	messageID := ps.ByName("id")
+
+	// Unpin the message
+	message, err := rt.db.UnpinMessage(r.Context(), ctx.UserID, messageID)
+	if err != nil {
+		replyError(w, ctx, err, "Failed to unpin message")
+		return
+	}
+	rt.notifyConversation(message.ConversationID, eventMessageUnpinned, map[string]string{
+		"messageId": messageID,
+		"userId":    ctx.UserID,
+	})
+
+	// Respond with success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{
+		"success": true,
+	})
+}
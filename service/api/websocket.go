@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+// wsProtocolVersion is the version of the JSON envelope exchanged on the WebSocket. Frames with a different version are
+// rejected.
+const wsProtocolVersion = 1
+
+const (
+	// wsWriteTimeout is the deadline for writing a single frame to the client.
+	wsWriteTimeout = 10 * time.Second
+
+	// wsPongTimeout is how long the connection can stay silent (no frames, no pongs) before it's considered dead.
+	wsPongTimeout = 60 * time.Second
+
+	// wsPingInterval is how often the server pings the client. It must be less than wsPongTimeout.
+	wsPingInterval = wsPongTimeout * 9 / 10
+
+	// wsMaxFrameSize is the maximum size of a frame sent by the client.
+	wsMaxFrameSize = 64 << 10
+
+	// wsReplyBuffer is the number of replies to commands queued for writing.
+	wsReplyBuffer = 16
+)
+
+// Commands sent by the client.
+const (
+	wsCommandSendMessage  = "message.send"
+	wsCommandReact        = "message.react"
+	wsCommandTyping       = "typing"
+	wsCommandMarkRead     = "conversation.read"
+	wsCommandAcknowledged = "ack"
+)
+
+// Frames sent by the server, besides the events (which are sent with their own type, e.g. "message.created").
+const (
+	wsReplyOK    = "ok"
+	wsReplyError = "error"
+)
+
+// eventTyping is relayed between members, without being stored.
+const eventTyping = "typing"
+
+// wsEnvelope is the JSON frame exchanged on the WebSocket in both directions. Commands carry a client-chosen ID, which
+// is copied in the reply; events pushed by the server carry the event ID.
+type wsEnvelope struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+
+	// Status and Error are set only on error replies. Status uses HTTP status codes, as for the REST routes.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	HandshakeTimeout: wsWriteTimeout,
+
+	// Authentication is done with the bearer header, not with cookies: cross-origin connections can't act on behalf of
+	// the user, so they are allowed as for the REST routes (see the CORS policy in cmd/webapi).
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClient is a WebSocket connection of a user. The read loop runs in the handler goroutine and executes commands; the
+// write loop is the only one writing to the connection, and it pushes events and replies.
+type wsClient struct {
+	rt      *_router
+	conn    *websocket.Conn
+	ctx     reqcontext.RequestContext
+	sub     *subscription
+	replies chan wsEnvelope
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// serveWebSocket upgrades the connection to a WebSocket that multiplexes the events of the conversations of the user
+// (as in getEvents) with commands sent by the client.
+func (rt *_router) serveWebSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params, ctx reqcontext.RequestContext) {
+	sub, ok := rt.events.subscribe(ctx.UserID)
+	if !ok {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer rt.events.unsubscribe(sub)
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied to the client
+		ctx.Logger.WithError(err).Debug("websocket upgrade failed")
+		return
+	}
+
+	c := &wsClient{
+		rt:      rt,
+		conn:    conn,
+		ctx:     ctx,
+		sub:     sub,
+		replies: make(chan wsEnvelope, wsReplyBuffer),
+		done:    make(chan struct{}),
+	}
+	go c.writeLoop()
+	c.readLoop(r.Context())
+}
+
+// close terminates the connection. It is safe to call it from both loops.
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// readLoop reads and executes the commands of the client, until the connection is closed.
+func (c *wsClient) readLoop(ctx context.Context) {
+	defer c.close()
+
+	c.conn.SetReadLimit(wsMaxFrameSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		var cmd wsEnvelope
+		if err := c.conn.ReadJSON(&cmd); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.reply(wsEnvelope{Type: wsReplyError, Status: http.StatusBadRequest, Error: "invalid JSON frame"})
+				continue
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.ctx.Logger.WithError(err).Debug("websocket closed")
+			}
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+
+		if cmd.Version != wsProtocolVersion {
+			c.reply(wsEnvelope{Type: wsReplyError, ID: cmd.ID, Status: http.StatusBadRequest,
+				Error: fmt.Sprintf("unsupported protocol version %d", cmd.Version)})
+			continue
+		}
+
+		result, err := c.execute(ctx, cmd)
+		if err != nil {
+			// As with replyError, the details of the error are only logged
+			status := errorStatus(err)
+			logger := c.ctx.Logger.WithError(err).WithField("command", cmd.Type)
+			if status == http.StatusInternalServerError {
+				logger.Error("websocket command failed")
+			} else {
+				logger.WithField("status", status).Debug("websocket command failed")
+			}
+			c.reply(wsEnvelope{Type: wsReplyError, ID: cmd.ID, Status: status, Error: http.StatusText(status)})
+		} else if cmd.Type != wsCommandAcknowledged {
+			c.reply(wsEnvelope{Type: wsReplyOK, ID: cmd.ID, Data: result})
+		}
+	}
+}
+
+// execute runs a command, returning the (JSON-encoded) result for the reply.
+func (c *wsClient) execute(ctx context.Context, cmd wsEnvelope) (json.RawMessage, error) {
+	var params struct {
+		ConversationID string `json:"conversationId"`
+		MessageID      string `json:"messageId"`
+		Content        string `json:"content"`
+		ReplyTo        string `json:"replyTo"`
+		Emoji          string `json:"emoji"`
+		EventID        uint64 `json:"eventId"`
+	}
+	if len(cmd.Data) > 0 {
+		if err := json.Unmarshal(cmd.Data, &params); err != nil {
+			return nil, fmt.Errorf("invalid command data: %w", errBadRequest)
+		}
+	}
+
+	var result interface{}
+	switch cmd.Type {
+	case wsCommandSendMessage:
+		message, err := c.rt.postMessage(ctx, c.ctx.UserID, database.NewMessage{
+			ConversationID: params.ConversationID,
+			Content:        params.Content,
+			ReplyTo:        params.ReplyTo,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = message
+
+	case wsCommandReact:
+		reaction, err := c.rt.reactToMessage(ctx, c.ctx.UserID, params.MessageID, params.Emoji)
+		if err != nil {
+			return nil, err
+		}
+		result = reaction
+
+	case wsCommandTyping:
+		if _, err := c.rt.db.GetConversation(ctx, c.ctx.UserID, params.ConversationID); err != nil {
+			return nil, err
+		}
+		c.rt.notifyConversation(params.ConversationID, eventTyping, map[string]string{
+			"userId": c.ctx.UserID,
+		})
+
+	case wsCommandMarkRead:
+		// The conversation can be omitted when a message is given
+		if params.ConversationID == "" && params.MessageID != "" {
+			message, err := c.rt.db.GetMessage(ctx, c.ctx.UserID, params.MessageID)
+			if err != nil {
+				return nil, err
+			}
+			params.ConversationID = message.ConversationID
+		}
+		receipt, err := c.rt.markRead(ctx, c.ctx.UserID, params.ConversationID, params.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		result = receipt
+
+	case wsCommandAcknowledged:
+		// Events are delivered at most once: acknowledgements are accepted, but not needed by the server
+		c.ctx.Logger.WithField("event", params.EventID).Debug("event acknowledged")
+
+	default:
+		return nil, fmt.Errorf("unknown command %q: %w", cmd.Type, errBadRequest)
+	}
+
+	if result == nil {
+		return nil, nil
+	}
+	return json.Marshal(result)
+}
+
+// reply queues a frame for the write loop, unless the connection is closing.
+func (c *wsClient) reply(env wsEnvelope) {
+	env.Version = wsProtocolVersion
+	select {
+	case c.replies <- env:
+	case <-c.done:
+	}
+}
+
+// writeLoop writes events, replies and pings to the client, until the connection or the subscription is closed.
+func (c *wsClient) writeLoop() {
+	defer c.close()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		var env wsEnvelope
+		var delivered *Event
+		select {
+		case <-c.done:
+			return
+
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+			continue
+
+		case ev, ok := <-c.sub.events:
+			if !ok {
+				// Closed by the hub: either the server is shutting down, or the client was too slow
+				_ = c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteTimeout))
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				c.ctx.Logger.WithError(err).Error("can't encode event")
+				continue
+			}
+			env = wsEnvelope{Version: wsProtocolVersion, Type: ev.Type, ID: fmt.Sprint(ev.ID), Data: data}
+			delivered = &ev
+
+		case env = <-c.replies:
+		}
+
+		_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := c.conn.WriteJSON(env); err != nil {
+			return
+		}
+		if delivered != nil {
+			c.rt.delivered(c.ctx.UserID, *delivered)
+		}
+	}
+}
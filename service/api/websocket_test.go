@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/database"
+	"github.com/gorilla/websocket"
+)
+
+// dialWebSocket connects to the WebSocket gateway of server as userID, closing the connection at the end of the test.
+func dialWebSocket(t *testing.T, server *httptest.Server, userID string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + userID}})
+	if err != nil {
+		t.Fatalf("connecting as %s: %v", userID, err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// readFrame reads the frames sent by the server until one of the given type, which it returns.
+func readFrame(t *testing.T, conn *websocket.Conn, frameType string) wsEnvelope {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var env wsEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			t.Fatalf("waiting for a %s frame: %v", frameType, err)
+		}
+		if env.Type == frameType {
+			return env
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	ctx := context.Background()
+	rt := newTestRouter(t)
+	alice, err := rt.db.GetOrCreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := rt.db.GetOrCreateUser(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := rt.db.GetOrCreateDirectConversation(ctx, alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt.wrap(rt.serveWebSocket, authenticated)(w, r, nil)
+	}))
+	defer server.Close()
+	defer rt.events.close()
+
+	aliceConn, bobConn := dialWebSocket(t, server, alice.ID), dialWebSocket(t, server, bob.ID)
+
+	// A message sent by a command is stored, and pushed to the other member as an event
+	err = aliceConn.WriteJSON(wsEnvelope{Version: wsProtocolVersion, Type: wsCommandSendMessage, ID: "1",
+		Data: json.RawMessage(`{"conversationId": "` + c.ID + `", "content": "hello"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := readFrame(t, aliceConn, wsReplyOK)
+	var sent database.Message
+	if err := json.Unmarshal(reply.Data, &sent); err != nil || reply.ID != "1" || sent.Content != "hello" {
+		t.Fatalf("reply = %+v (%v), want the message sent", reply, err)
+	}
+	event := readFrame(t, bobConn, eventMessageCreated)
+	var ev struct {
+		Data database.Message `json:"data"`
+	}
+	if err := json.Unmarshal(event.Data, &ev); err != nil || ev.Data.ID != sent.ID {
+		t.Errorf("event = %s (%v), want message %s", event.Data, err, sent.ID)
+	}
+
+	// Failing commands get an error reply, and the connection stays open
+	for _, tt := range []struct {
+		cmd    wsEnvelope
+		status int
+	}{
+		{wsEnvelope{Version: wsProtocolVersion + 1, Type: wsCommandTyping, ID: "2"}, http.StatusBadRequest},
+		{wsEnvelope{Version: wsProtocolVersion, Type: "unknown", ID: "3"}, http.StatusBadRequest},
+		{wsEnvelope{Version: wsProtocolVersion, Type: wsCommandSendMessage, ID: "4",
+			Data: json.RawMessage(`{"conversationId": "` + c.ID + `"}`)}, http.StatusBadRequest},
+		{wsEnvelope{Version: wsProtocolVersion, Type: wsCommandSendMessage, ID: "5",
+			Data: json.RawMessage(`{"conversationId": "nonexistent0", "content": "hello"}`)}, http.StatusNotFound},
+	} {
+		if err := aliceConn.WriteJSON(tt.cmd); err != nil {
+			t.Fatal(err)
+		}
+		reply := readFrame(t, aliceConn, wsReplyError)
+		if reply.ID != tt.cmd.ID || reply.Status != tt.status {
+			t.Errorf("reply to %+v = %+v, want status %d", tt.cmd, reply, tt.status)
+		}
+	}
+
+	// Connecting needs the same bearer identifier as the REST routes
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("connecting without a bearer = %v, want 401 Unauthorized", err)
+	}
+}
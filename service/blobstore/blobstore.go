@@ -0,0 +1,105 @@
+/*
+Package blobstore stores binary content (photos, attachments) outside the database. Blobs are content-addressed: the key
+of a blob is the hex SHA-256 of its content, so identical uploads are stored once, and a blob never changes once
+stored.
+
+Two implementations are available: NewFilesystem stores blobs in a local directory, NewS3 in a bucket of an
+S3-compatible service (AWS S3, MinIO, ...). For local development, a MinIO container is a good stand-in for S3:
+
+	docker run -p 9000:9000 -e MINIO_ROOT_USER=minio -e MINIO_ROOT_PASSWORD=minio123 minio/minio server /data
+
+The store keeps no record of who uses a blob: reference counting and garbage collection are done by the caller (see the
+blobs table in the database package).
+*/
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var (
+	// ErrNotFound is returned when the blob does not exist.
+	ErrNotFound = errors.New("blob not found")
+
+	// ErrInvalidKey is returned when the key is not a hex SHA-256.
+	ErrInvalidKey = errors.New("invalid blob key")
+
+	// ErrChecksumMismatch is returned by Put when the content does not match the key or the size.
+	ErrChecksumMismatch = errors.New("blob content does not match its key")
+)
+
+// Info describes a stored blob.
+type Info struct {
+	Key  string
+	Size int64
+}
+
+// BlobStore is a content-addressed storage. Implementations are safe for concurrent use.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, which must be the hex SHA-256 of the content: the content is
+	// verified while it is stored, and ErrChecksumMismatch is returned if it does not match. Storing a blob that
+	// already exists does nothing.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Get opens a blob for reading. The reader is seekable, so that ranges can be served without reading the whole
+	// blob; it must be closed by the caller.
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+
+	// Stat returns the information about a blob.
+	Stat(ctx context.Context, key string) (Info, error)
+
+	// Delete removes a blob. Deleting a blob that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Key returns the key of the given content.
+func Key(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkKey returns ErrInvalidKey if key is not a lowercase hex SHA-256. Keys are used in file names and URLs, so they
+// must be validated before use.
+func checkKey(key string) error {
+	if len(key) != sha256.Size*2 {
+		return fmt.Errorf("%q: %w", key, ErrInvalidKey)
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return fmt.Errorf("%q: %w", key, ErrInvalidKey)
+		}
+	}
+	return nil
+}
+
+// verifyingReader hashes the content while it's read, to check it against the key at the end.
+type verifyingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func newVerifyingReader(r io.Reader) *verifyingReader {
+	return &verifyingReader{r: r, h: sha256.New()}
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.n += int64(n)
+	_, _ = v.h.Write(p[:n])
+	return n, err
+}
+
+// verify checks that the content read so far matches the key and the size.
+func (v *verifyingReader) verify(key string, size int64) error {
+	if got := hex.EncodeToString(v.h.Sum(nil)); got != key || v.n != size {
+		return fmt.Errorf("%s: got %d bytes with key %s: %w", key, v.n, got, ErrChecksumMismatch)
+	}
+	return nil
+}
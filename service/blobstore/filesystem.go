@@ -0,0 +1,108 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// filesystem stores each blob in a file named after its key, in a subdirectory named after the first two characters
+// of the key (to keep directories small). Blobs are written to a temporary directory first, and then renamed in place:
+// readers never see partial blobs.
+type filesystem struct {
+	dir string
+}
+
+// NewFilesystem returns a BlobStore keeping the blobs in dir, which is created if needed. The temporary directory is
+// inside dir, so that blobs can be renamed in place atomically.
+func NewFilesystem(dir string) (BlobStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0o700); err != nil {
+		return nil, fmt.Errorf("creating blob directory: %w", err)
+	}
+	return &filesystem{dir: dir}, nil
+}
+
+func (s *filesystem) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+func (s *filesystem) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.path(key)); err == nil {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), key+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// After the rename, this fails harmlessly
+		_ = os.Remove(tmp.Name())
+	}()
+
+	v := newVerifyingReader(io.LimitReader(r, size+1))
+	if _, err := io.Copy(tmp, v); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := v.verify(key, size); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path(key)), 0o700); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s *filesystem) Get(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *filesystem) Stat(_ context.Context, key string) (Info, error) {
+	if err := checkKey(key); err != nil {
+		return Info{}, err
+	}
+	fi, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, fmt.Errorf("%s: %w", key, ErrNotFound)
+	} else if err != nil {
+		return Info{}, err
+	}
+	return Info{Key: key, Size: fi.Size()}, nil
+}
+
+func (s *filesystem) Delete(_ context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingReader fails every read: it stands for content that must not be read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("content read")
+}
+
+// tmpFiles returns the files left in the temporary directory of a filesystem store.
+func tmpFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestFilesystem(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFilesystem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("the quick brown fox jumps over the lazy dog")
+	key := Key(content)
+
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat of a missing blob = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing blob = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, key[:2], key)); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("stored file = %q, %v, want %q", data, err, content)
+	}
+	if names := tmpFiles(t, dir); len(names) != 0 {
+		t.Errorf("temporary files left: %v", names)
+	}
+
+	// The same content again is not read, nor stored twice
+	if err := store.Put(ctx, key, failingReader{}, int64(len(content))); err != nil {
+		t.Errorf("Put of an existing blob = %v", err)
+	}
+
+	info, err := store.Stat(ctx, key)
+	if err != nil || info != (Info{Key: key, Size: int64(len(content))}) {
+		t.Errorf("Stat = %v, %v, want size %d", info, err, len(content))
+	}
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Seek(-8, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(r); err != nil || string(data) != "lazy dog" {
+		t.Errorf("read from the end = %q, %v, want %q", data, err, "lazy dog")
+	}
+	_ = r.Close()
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing blob = %v", err)
+	}
+}
+
+func TestFilesystemChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFilesystem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("expected content")
+	key := Key(content)
+
+	tests := []struct {
+		name string
+		data []byte
+		size int64
+	}{
+		{"other content", []byte("unexpected content"), int64(len(content))},
+		{"short", content[:4], int64(len(content))},
+		{"long", append(append([]byte{}, content...), "and more"...), int64(len(content))},
+		{"wrong size", content, int64(len(content)) - 1},
+	}
+	for _, tt := range tests {
+		err := store.Put(ctx, key, bytes.NewReader(tt.data), tt.size)
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: Put = %v, want ErrChecksumMismatch", tt.name, err)
+		}
+		// The content is verified before being renamed in place, and the temporary file is removed
+		if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Stat = %v, want ErrNotFound", tt.name, err)
+		}
+		if names := tmpFiles(t, dir); len(names) != 0 {
+			t.Errorf("%s: temporary files left: %v", tt.name, names)
+		}
+	}
+}
+
+func TestFilesystemInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "../../etc/passwd", "tmp", Key(nil)[:63] + "G"} {
+		if err := store.Put(ctx, key, bytes.NewReader(nil), 0); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config is the configuration of an S3-compatible bucket.
+type S3Config struct {
+	// Endpoint is the base URL of the service, e.g. `https://s3.eu-south-1.amazonaws.com` or `http://localhost:9000`.
+	Endpoint string
+
+	// Region is the region of the bucket, used in request signatures (MinIO accepts `us-east-1`).
+	Region string
+
+	Bucket string
+
+	// Prefix is prepended to the keys, to share a bucket with other data (e.g., `blobs/`).
+	Prefix string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// PathStyle addresses the bucket as `<endpoint>/<bucket>` instead of `<bucket>.<endpoint host>`. It is required by
+	// most self-hosted services, such as MinIO.
+	PathStyle bool
+}
+
+// s3Timeout bounds requests without a body to stream (HEAD, DELETE). Uploads and downloads are bound by the context.
+const s3Timeout = 30 * time.Second
+
+// s3 stores blobs as objects of a bucket, using the S3 REST API with Signature Version 4 authentication.
+type s3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+}
+
+// NewS3 returns a BlobStore keeping the blobs in an S3-compatible bucket. The bucket must already exist.
+func NewS3(cfg S3Config) (BlobStore, error) {
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	switch {
+	case cfg.Bucket == "":
+		return nil, errors.New("S3 bucket is required")
+	case cfg.Region == "":
+		return nil, errors.New("S3 region is required")
+	case cfg.AccessKeyID == "" || cfg.SecretAccessKey == "":
+		return nil, errors.New("S3 credentials are required")
+	}
+	return &s3{cfg: cfg, base: base, client: &http.Client{}}, nil
+}
+
+// objectURL returns the URL of the object of a blob.
+func (s *s3) objectURL(key string) *url.URL {
+	u := *s.base
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + s.cfg.Prefix + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Prefix + key
+	}
+	return &u
+}
+
+// do signs and sends a request for the object of a blob. payloadHash is the hex SHA-256 of the body.
+func (s *s3) do(ctx context.Context, method string, key string, body io.Reader, size int64, payloadHash string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	signV4(req, payloadHash, s.cfg.Region, s.cfg.AccessKeyID, s.cfg.SecretAccessKey, time.Now())
+	return s.client.Do(req)
+}
+
+// errorResponse reads the error returned by the service, and closes the response.
+func errorResponse(key string, resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if strings.Contains(string(msg), "XAmzContentSHA256Mismatch") {
+		// The content does not match the hash signed with the request, i.e. the key
+		return fmt.Errorf("S3 %s: %s: %w", key, resp.Status, ErrChecksumMismatch)
+	}
+	return fmt.Errorf("S3 %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (s *s3) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if _, err := s.Stat(ctx, key); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// The key is the hash of the content, and it's signed as such: S3 rejects the upload if the content does not
+	// match. The content is hashed locally too, to remove the object in case the service does not check the hash. A
+	// rejected upload may not have been read in full, so only the service knows why it was rejected.
+	v := newVerifyingReader(io.LimitReader(r, size))
+	resp, err := s.do(ctx, http.MethodPut, key, v, size, key, http.Header{
+		"Content-Type": {"application/octet-stream"},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errorResponse(key, resp)
+	}
+	_ = resp.Body.Close()
+	if err := v.verify(key, size); err != nil {
+		_ = s.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
+
+func (s *s3) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Object{ctx: ctx, store: s, key: key, size: info.Size}, nil
+}
+
+func (s *s3) Stat(ctx context.Context, key string) (Info, error) {
+	if err := checkKey(key); err != nil {
+		return Info{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s3Timeout)
+	defer cancel()
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Info{}, errorResponse(key, resp)
+	}
+	_ = resp.Body.Close()
+	return Info{Key: key, Size: resp.ContentLength}, nil
+}
+
+func (s *s3) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s3Timeout)
+	defer cancel()
+
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return errorResponse(key, resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// s3Object reads an object with ranged GET requests: a request is started at the first Read after opening or
+// seeking, from the current offset to the end of the object.
+type s3Object struct {
+	ctx    context.Context
+	store  *s3
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		resp, err := o.store.do(o.ctx, http.MethodGet, o.key, nil, 0, emptyPayloadHash, http.Header{
+			"Range": {"bytes=" + strconv.FormatInt(o.offset, 10) + "-"},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+			return 0, errorResponse(o.key, resp)
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = o.offset + offset
+	case io.SeekEnd:
+		abs = o.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	if abs != o.offset && o.body != nil {
+		_ = o.body.Close()
+		o.body = nil
+	}
+	o.offset = abs
+	return abs, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,242 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal stand-in for an S3 bucket, addressed in path style: it stores the objects in memory, and serves
+// PUT, HEAD, ranged GET and DELETE requests.
+type fakeS3 struct {
+	t      *testing.T
+	bucket string
+
+	// checkHash makes uploads fail if the content does not match X-Amz-Content-Sha256, as S3 does
+	checkHash bool
+
+	// denyPut makes uploads fail before their content is read, as S3 does for requests not allowed
+	denyPut bool
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	ranges  []string
+}
+
+func newFakeS3(t *testing.T, checkHash bool) (*fakeS3, BlobStore) {
+	f := &fakeS3{t: t, bucket: "wasatext", checkHash: checkHash, objects: map[string][]byte{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3(S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          f.bucket,
+		Prefix:          "blobs/",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, store
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/blobs/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, exists := f.objects[name]
+	switch r.Method {
+	case http.MethodPut:
+		if f.denyPut {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256(body)
+		if f.checkHash && hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[name] = body
+	case http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		rng := r.Header.Get("Range")
+		f.ranges = append(f.ranges, rng)
+		start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		if err != nil || start >= len(data) {
+			http.Error(w, "InvalidRange", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(data)-1)+"/"+strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(data[start:])
+	case http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) object(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[key]
+	return data, ok
+}
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	f, store := newFakeS3(t, true)
+	content := []byte("the quick brown fox jumps over the lazy dog")
+	key := Key(content)
+
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat of a missing blob = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing blob = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, ok := f.object(key); !ok || !bytes.Equal(data, content) {
+		t.Fatalf("stored object = %q, want %q", data, content)
+	}
+	info, err := store.Stat(ctx, key)
+	if err != nil || info != (Info{Key: key, Size: int64(len(content))}) {
+		t.Errorf("Stat = %v, %v, want size %d", info, err, len(content))
+	}
+
+	// Whole blob, then a range from the middle
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Get = %q, %v, want %q", data, err, content)
+	}
+	if _, err := r.Seek(-8, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(r)
+	if err != nil || string(data) != "lazy dog" {
+		t.Errorf("read from the end = %q, %v, want %q", data, err, "lazy dog")
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+	if want := []string{"bytes=0-", "bytes=35-"}; strings.Join(f.ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("ranges requested = %v, want %v", f.ranges, want)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := f.object(key); ok {
+		t.Error("object still stored after Delete")
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing blob = %v", err)
+	}
+}
+
+func TestS3ChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("expected content")
+	key := Key(content)
+	wrong := []byte("unexpected content")
+
+	for _, checkHash := range []bool{true, false} {
+		f, store := newFakeS3(t, checkHash)
+		err := store.Put(ctx, key, bytes.NewReader(wrong), int64(len(wrong)))
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("Put with the service checking: %v: %v, want ErrChecksumMismatch", checkHash, err)
+		}
+		// A service that does not check the hash accepts the upload: the object must be removed
+		if _, ok := f.object(key); ok {
+			t.Errorf("Put with the service checking: %v: mismatching object stored", checkHash)
+		}
+
+		// Short content
+		err = store.Put(ctx, key, bytes.NewReader(content[:4]), int64(len(content)))
+		if err == nil {
+			t.Errorf("Put with the service checking: %v: short content accepted", checkHash)
+		}
+		if _, ok := f.object(key); ok {
+			t.Errorf("Put with the service checking: %v: short object stored", checkHash)
+		}
+	}
+}
+
+func TestS3Rejected(t *testing.T) {
+	ctx := context.Background()
+	f, store := newFakeS3(t, true)
+	f.denyPut = true
+
+	// The service rejects the upload before reading the content, which is not fully read: the error of the service
+	// is returned, not a mismatch of the content
+	content := []byte("some content")
+	r, w := io.Pipe()
+	go func() {
+		_, _ = w.Write(content[:4])
+	}()
+	err := store.Put(ctx, Key(content), r, int64(len(content)))
+	_ = w.Close()
+	if err == nil || errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put = %v, want the access denied by the service", err)
+	}
+	if _, ok := f.object(Key(content)); ok {
+		t.Error("rejected object stored")
+	}
+}
+
+func TestS3InvalidKey(t *testing.T) {
+	ctx := context.Background()
+	_, store := newFakeS3(t, true)
+	for _, key := range []string{"", "../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63)} {
+		if _, err := store.Stat(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Stat(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
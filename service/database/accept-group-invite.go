@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// AcceptGroupInvite adds the user to the group of an invite, as a plain member, and records it in the group with a
+// system message. ErrNotFound is returned if the invite does not exist or no longer works, and ErrConflict if the user
+// is already a member (the invite is not used then).
+func (db *appdbimpl) AcceptGroupInvite(ctx context.Context, userID string, token string) (group Group, message Message, err error) {
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		invite, err := scanInvite(tx.QueryRowContext(ctx, inviteSelect+" WHERE token = ?", token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("invite %s: %w", token, ErrNotFound)
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case invite.Revoked:
+			return fmt.Errorf("invite %s has been revoked: %w", token, ErrNotFound)
+		case invite.ExpiresAt != nil && invite.ExpiresAt.UnixMilli() <= now():
+			return fmt.Errorf("invite %s has expired: %w", token, ErrNotFound)
+		case invite.MaxUses > 0 && invite.Uses >= invite.MaxUses:
+			return fmt.Errorf("invite %s has been used up: %w", token, ErrNotFound)
+		}
+
+		err = checkMember(ctx, tx, invite.GroupID, userID)
+		if err == nil {
+			return fmt.Errorf("already in group %s: %w", invite.GroupID, ErrConflict)
+		} else if !errors.Is(err, ErrForbidden) {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE group_invites SET uses = uses + 1 WHERE token = ?", token); err != nil {
+			return err
+		}
+		if err := addGroupMember(ctx, tx, invite.GroupID, userID, RoleMember); err != nil {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, invite.GroupID, userID, SystemEvent{Action: ActionMemberJoined})
+		if err != nil {
+			return err
+		}
+		group, err = getGroup(ctx, tx, invite.GroupID)
+		return err
+	})
+	return group, message, err
+}
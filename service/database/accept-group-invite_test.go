@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupInvites(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	owner, bob := createUser(t, db, "owner"), createUser(t, db, "bob")
+	carol, dave, eve := createUser(t, db, "carol"), createUser(t, db, "dave"), createUser(t, db, "eve")
+	group, err := db.CreateGroup(ctx, owner.ID, "friends", []string{bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only admins create and revoke invites
+	if _, err := db.CreateGroupInvite(ctx, bob.ID, group.ID, time.Time{}, 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("invite created by a plain member = %v, want ErrForbidden", err)
+	}
+	once, err := db.CreateGroupInvite(ctx, owner.ID, group.ID, time.Time{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if once.GroupID != group.ID || once.CreatedBy != owner.ID || once.MaxUses != 1 || once.Uses != 0 || once.ExpiresAt != nil {
+		t.Errorf("invite = %+v", once)
+	}
+	if err := db.RevokeGroupInvite(ctx, bob.ID, group.ID, once.Token); !errors.Is(err, ErrForbidden) {
+		t.Errorf("invite revoked by a plain member = %v, want ErrForbidden", err)
+	}
+
+	// Accepting it when already a member doesn't use it
+	if _, _, err := db.AcceptGroupInvite(ctx, bob.ID, once.Token); !errors.Is(err, ErrConflict) {
+		t.Errorf("invite accepted by a member = %v, want ErrConflict", err)
+	}
+	g, m, err := db.AcceptGroupInvite(ctx, carol.ID, once.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ID != group.ID || len(g.Members) != 3 || m.System == nil || m.System.Action != ActionMemberJoined {
+		t.Errorf("AcceptGroupInvite = %+v, %+v, want carol in the group", g, m.System)
+	}
+	if role := memberRole(t, db, group.ID, carol.ID); role != RoleMember {
+		t.Errorf("carol joined as %s, want member", role)
+	}
+
+	// Used up
+	if _, _, err := db.AcceptGroupInvite(ctx, dave.ID, once.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("used up invite = %v, want ErrNotFound", err)
+	}
+
+	// Expired
+	expiring, err := db.CreateGroupInvite(ctx, owner.ID, group.ID, start.Add(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setTime(t, start.Add(time.Hour))
+	if _, _, err := db.AcceptGroupInvite(ctx, dave.ID, expiring.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired invite = %v, want ErrNotFound", err)
+	}
+
+	// Revoked, twice
+	revoked, err := db.CreateGroupInvite(ctx, owner.ID, group.ID, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.AcceptGroupInvite(ctx, dave.ID, revoked.Token); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.RevokeGroupInvite(ctx, owner.ID, group.ID, revoked.Token); err != nil {
+			t.Fatalf("revoking the invite: %v", err)
+		}
+	}
+	if _, _, err := db.AcceptGroupInvite(ctx, eve.ID, revoked.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoked invite = %v, want ErrNotFound", err)
+	}
+	if err := db.RevokeGroupInvite(ctx, owner.ID, group.ID, "nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoking an unknown invite = %v, want ErrNotFound", err)
+	}
+	if _, _, err := db.AcceptGroupInvite(ctx, eve.ID, "nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown invite = %v, want ErrNotFound", err)
+	}
+
+	// The admins see them all, with their uses
+	invites, err := db.GetGroupInvites(ctx, owner.ID, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uses := map[string]int{}
+	for _, i := range invites {
+		uses[i.Token] = i.Uses
+	}
+	if len(invites) != 3 || uses[once.Token] != 1 || uses[expiring.Token] != 0 || uses[revoked.Token] != 1 {
+		t.Errorf("invites = %+v", invites)
+	}
+}
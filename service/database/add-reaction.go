@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// AddReaction adds a reaction of the user to a message with an emoji. Users can react with several distinct emoji;
+// reacting again with the same one does nothing.
+func (db *appdbimpl) AddReaction(ctx context.Context, userID string, messageID string, emoji string) (Reaction, error) {
+	var r = Reaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		message, err := getVisibleMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if message.Deleted {
+			return fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		} else if message.Kind == MessageSystem {
+			return fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO reactions (message_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (message_id, user_id, emoji) DO NOTHING`,
+			messageID, userID, emoji, now())
+		if err != nil {
+			return err
+		}
+
+		var createdAt int64
+		err = tx.QueryRowContext(ctx, "SELECT created_at FROM reactions WHERE message_id = ? AND user_id = ? AND emoji = ?",
+			messageID, userID, emoji).Scan(&createdAt)
+		r.Timestamp = fromMillis(createdAt)
+		return err
+	})
+	return r, err
+}
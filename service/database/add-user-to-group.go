@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// AddUserToGroup adds newMemberID to a group the user is an admin of, and returns the system message recording it.
+// ErrConflict is returned if newMemberID is already a member.
+func (db *appdbimpl) AddUserToGroup(ctx context.Context, userID string, groupID string, newMemberID string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupAdmin(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		err := checkMember(ctx, tx, groupID, newMemberID)
+		if err == nil {
+			return fmt.Errorf("user %s already in group %s: %w", newMemberID, groupID, ErrConflict)
+		} else if !errors.Is(err, ErrForbidden) {
+			return err
+		}
+
+		if err := addGroupMember(ctx, tx, groupID, newMemberID, RoleMember); err != nil {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, groupID, userID, SystemEvent{
+			Action:   ActionMemberAdded,
+			TargetID: newMemberID,
+		})
+		return err
+	})
+	return message, err
+}
+
+// addMember adds a user to a conversation. ErrNotFound is returned if the user does not exist.
+func addMember(ctx context.Context, tx *sql.Tx, conversationID string, userID string) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO conversation_members (conversation_id, user_id, joined_at) VALUES (?, ?, ?)`,
+		conversationID, userID, now())
+	return err
+}
+
+// addGroupMember adds a user to a group with the given role.
+func addGroupMember(ctx context.Context, tx *sql.Tx, groupID string, userID string, role string) error {
+	if err := addMember(ctx, tx, groupID, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, ?)", groupID, userID, role)
+	return err
+}
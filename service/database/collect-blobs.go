@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// collectBatch is the maximum number of blobs collected by a single call of CollectBlobs.
+const collectBatch = 100
+
+// CollectBlobs removes the blobs released before releasedBefore. A failed removal keeps the blob, to retry later.
+//
+// The triggers dropping the last reference to a blob leave its release time empty: it's set to the current time here,
+// so that the grace period is measured with the same clock as releasedBefore.
+func (db *appdbimpl) CollectBlobs(ctx context.Context, releasedBefore time.Time, remove func(key string) error) (int, error) {
+	_, err := db.c.ExecContext(ctx, "UPDATE blobs SET released_at = ? WHERE refcount = 0 AND released_at IS NULL", now())
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := releasedBefore.UnixMilli()
+	rows, err := db.c.QueryContext(ctx, `
+		SELECT key FROM blobs WHERE refcount = 0 AND released_at <= ? ORDER BY released_at LIMIT ?`, cutoff, collectBatch)
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	_ = rows.Close()
+
+	collected := 0
+	for _, key := range keys {
+		removed, err := db.collectBlob(ctx, key, cutoff, remove)
+		if err != nil {
+			return collected, err
+		} else if removed {
+			collected++
+		}
+	}
+	return collected, nil
+}
+
+// collectBlob deletes the row of a blob released before cutoff, and then removes the blob from the store, outside of
+// the transaction (removing may take a network round trip). Meanwhile, RegisterBlob waits: the same content is then
+// uploaded again, rather than found in the store just before being removed. If the removal fails, the row is restored.
+func (db *appdbimpl) collectBlob(ctx context.Context, key string, cutoff int64, remove func(key string) error) (bool, error) {
+	done := make(chan struct{})
+	db.collectingMu.Lock()
+	db.collecting[key] = done
+	db.collectingMu.Unlock()
+	defer func() {
+		db.collectingMu.Lock()
+		delete(db.collecting, key)
+		db.collectingMu.Unlock()
+		close(done)
+	}()
+
+	var size, releasedAt int64
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		// The blob may have been referenced (or registered again) in the meantime
+		err := tx.QueryRowContext(ctx, "SELECT size, released_at FROM blobs WHERE key = ? AND refcount = 0 AND released_at <= ?",
+			key, cutoff).Scan(&size, &releasedAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM blobs WHERE key = ?", key)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := remove(key); err != nil {
+		_, restoreErr := db.c.ExecContext(ctx, "INSERT INTO blobs (key, size, refcount, released_at) VALUES (?, ?, 0, ?)",
+			key, size, releasedAt)
+		return false, errors.Join(err, restoreErr)
+	}
+	return true, nil
+}
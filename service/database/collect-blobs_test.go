@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+)
+
+// blobState returns the reference count and the release time (Unix milliseconds, 0 if not set) of a blob.
+func blobState(t *testing.T, db *appdbimpl, key string) (int, int64) {
+	t.Helper()
+	var refcount int
+	var releasedAt *int64
+	err := db.c.QueryRow("SELECT refcount, released_at FROM blobs WHERE key = ?", key).Scan(&refcount, &releasedAt)
+	if err != nil {
+		t.Fatalf("blob %s: %v", key, err)
+	}
+	if releasedAt == nil {
+		return refcount, 0
+	}
+	return refcount, *releasedAt
+}
+
+// collect runs CollectBlobs with the given grace period, and returns the keys removed.
+func collect(t *testing.T, db *appdbimpl, grace time.Duration) []string {
+	t.Helper()
+	var removed []string
+	_, err := db.CollectBlobs(context.Background(), globaltime.Now().Add(-grace), func(key string) error {
+		removed = append(removed, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CollectBlobs: %v", err)
+	}
+	return removed
+}
+
+func TestCollectBlobs(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	c := startConversation(t, db, alice.ID, bob.ID)
+
+	// Registered: released right away, until referenced
+	const photo, file = "photo", "file"
+	for _, key := range []string{photo, file} {
+		if err := db.RegisterBlob(ctx, key, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refcount, releasedAt := blobState(t, db, file); refcount != 0 || releasedAt != start.UnixMilli() {
+		t.Errorf("registered blob: refcount %d, released at %d, want 0, %d", refcount, releasedAt, start.UnixMilli())
+	}
+	if err := db.SaveUserPhoto(ctx, alice.ID, photo, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := db.SaveMessage(ctx, alice.ID, NewMessage{ConversationID: c.ID, Attachment: &Attachment{
+		FileName: "notes.txt", MIMEType: "text/plain", Size: 10, Checksum: file,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ForwardMessage(ctx, alice.ID, m.ID, c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, releasedAt := blobState(t, db, file); refcount != 2 || releasedAt != 0 {
+		t.Errorf("attached twice: refcount %d, released at %d, want 2, unset", refcount, releasedAt)
+	}
+
+	// Dropping one reference keeps the blob, dropping the last one releases it
+	if err := db.DeleteMessage(ctx, alice.ID, m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, _ := blobState(t, db, file); refcount != 1 {
+		t.Errorf("after deleting a message: refcount %d, want 1", refcount)
+	}
+	if err := db.RegisterBlob(ctx, "other", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveUserPhoto(ctx, alice.ID, "other", "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, releasedAt := blobState(t, db, photo); refcount != 0 || releasedAt != 0 {
+		t.Errorf("replaced photo: refcount %d, released at %d, want 0, unset until collected", refcount, releasedAt)
+	}
+
+	// The release time is set by the collector, with the application clock: the grace period starts then
+	stamped := start.Add(time.Hour)
+	setTime(t, stamped)
+	if removed := collect(t, db, time.Minute); len(removed) != 0 {
+		t.Errorf("collected %v within the grace period", removed)
+	}
+	if _, releasedAt := blobState(t, db, photo); releasedAt != stamped.UnixMilli() {
+		t.Errorf("released at %d, want %d", releasedAt, stamped.UnixMilli())
+	}
+	setTime(t, stamped.Add(time.Minute))
+	if removed := collect(t, db, 2*time.Minute); len(removed) != 0 {
+		t.Errorf("collected %v within the grace period", removed)
+	}
+	setTime(t, stamped.Add(2*time.Minute))
+	if removed := collect(t, db, 2*time.Minute); len(removed) != 1 || removed[0] != photo {
+		t.Errorf("collected %v, want only %s", removed, photo)
+	}
+	if refcount, _ := blobState(t, db, file); refcount != 1 {
+		t.Errorf("referenced blob: refcount %d, want 1", refcount)
+	}
+
+	// A failed removal keeps the blob, to retry later
+	if err := db.SaveUserPhoto(ctx, alice.ID, file, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	setTime(t, stamped.Add(time.Hour))
+	collect(t, db, time.Minute)
+	setTime(t, stamped.Add(2*time.Hour))
+	failure := errors.New("store unavailable")
+	_, err = db.CollectBlobs(ctx, globaltime.Now().Add(-time.Minute), func(string) error { return failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("CollectBlobs = %v, want the error of remove", err)
+	}
+	if refcount, _ := blobState(t, db, "other"); refcount != 0 {
+		t.Errorf("blob after a failed removal: refcount %d, want 0", refcount)
+	}
+	if removed := collect(t, db, time.Minute); len(removed) != 1 || removed[0] != "other" {
+		t.Errorf("collected %v, want other", removed)
+	}
+}
+
+func TestCollectBlobsOutsideTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	if err := db.RegisterBlob(ctx, "photo", 10); err != nil {
+		t.Fatal(err)
+	}
+	setTime(t, start.Add(time.Hour))
+
+	registered := make(chan error, 1)
+	_, err := db.CollectBlobs(ctx, globaltime.Now().Add(-time.Minute), func(key string) error {
+		// The database is not locked while the store removes the blob
+		if err := db.RegisterBlob(ctx, "other", 10); err != nil {
+			t.Errorf("RegisterBlob during the removal: %v", err)
+		}
+
+		// Registering the same content waits for the removal, to upload it again
+		go func() {
+			registered <- db.RegisterBlob(ctx, key, 10)
+		}()
+		select {
+		case err := <-registered:
+			t.Errorf("RegisterBlob of the blob being removed returned %v before the removal", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-registered; err != nil {
+		t.Fatalf("RegisterBlob after the removal: %v", err)
+	}
+	if refcount, releasedAt := blobState(t, db, "photo"); refcount != 0 || releasedAt != globaltime.Now().UnixMilli() {
+		t.Errorf("registered again: refcount %d, released at %d, want 0, %d", refcount, releasedAt, globaltime.Now().UnixMilli())
+	}
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+	"github.com/mattn/go-sqlite3"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx, so helpers can be used inside and outside transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// idAlphabet contains the characters allowed in identifiers (see the patterns in doc/api.yaml).
+const idAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-"
+
+// newID returns a new random identifier of 12 characters.
+func newID() (string, error) {
+	var buf [12]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("generating identifier: %w", err)
+	}
+	for i, b := range buf {
+		buf[i] = idAlphabet[int(b)%len(idAlphabet)]
+	}
+	return string(buf[:]), nil
+}
+
+// now returns the current time as stored in the database (Unix milliseconds).
+func now() int64 {
+	return globaltime.Now().UnixMilli()
+}
+
+// fromMillis converts a timestamp stored in the database to time.Time.
+func fromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+// withTx runs fn inside a transaction, committing it if fn returns nil and rolling it back otherwise.
+func (db *appdbimpl) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.c.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkMember returns ErrNotFound if the conversation does not exist, and ErrForbidden if the user is not a member.
+func checkMember(ctx context.Context, q querier, conversationID string, userID string) error {
+	var exists, member bool
+	err := q.QueryRowContext(ctx, `
+		SELECT TRUE, EXISTS(SELECT 1 FROM conversation_members WHERE conversation_id = c.id AND user_id = ?)
+		FROM conversations c WHERE c.id = ?`, userID, conversationID).Scan(&exists, &member)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("conversation %s: %w", conversationID, ErrNotFound)
+	} else if err != nil {
+		return err
+	} else if !member {
+		return fmt.Errorf("not a member of conversation %s: %w", conversationID, ErrForbidden)
+	}
+	return nil
+}
+
+// checkGroupMember is like checkMember, but it also returns ErrNotFound if the conversation is not a group.
+func checkGroupMember(ctx context.Context, q querier, groupID string, userID string) error {
+	var isGroup bool
+	err := q.QueryRowContext(ctx, "SELECT is_group FROM conversations WHERE id = ?", groupID).Scan(&isGroup)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !isGroup) {
+		return fmt.Errorf("group %s: %w", groupID, ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	return checkMember(ctx, q, groupID, userID)
+}
+
+// groupRole returns the role of the user in a group, with the errors of checkGroupMember if they are not a member.
+func groupRole(ctx context.Context, q querier, groupID string, userID string) (string, error) {
+	if err := checkGroupMember(ctx, q, groupID, userID); err != nil {
+		return "", err
+	}
+	var role string
+	err := q.QueryRowContext(ctx, "SELECT role FROM group_members WHERE group_id = ? AND user_id = ?", groupID, userID).
+		Scan(&role)
+	return role, err
+}
+
+// checkGroupAdmin is like checkGroupMember, but it also returns ErrForbidden if the user is a plain member.
+func checkGroupAdmin(ctx context.Context, q querier, groupID string, userID string) error {
+	role, err := groupRole(ctx, q, groupID, userID)
+	if err != nil {
+		return err
+	} else if role == RoleMember {
+		return fmt.Errorf("not an admin of group %s: %w", groupID, ErrForbidden)
+	}
+	return nil
+}
+
+// memberRoles returns the role of the user in a group and the one of another member, memberID. ErrNotFound is returned
+// if memberID is not a member of the group.
+func memberRoles(ctx context.Context, q querier, groupID string, userID string, memberID string) (role string, memberRole string, err error) {
+	if role, err = groupRole(ctx, q, groupID, userID); err != nil {
+		return "", "", err
+	}
+	err = q.QueryRowContext(ctx, "SELECT role FROM group_members WHERE group_id = ? AND user_id = ?", groupID, memberID).
+		Scan(&memberRole)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", fmt.Errorf("user %s in group %s: %w", memberID, groupID, ErrNotFound)
+	}
+	return role, memberRole, err
+}
+
+// roleRank orders the roles of group members, higher for more privileged ones.
+func roleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 2
+	case RoleAdmin:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isUniqueViolation reports whether err is a UNIQUE (or PRIMARY KEY) constraint failure.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// getVisibleMessage returns a message of a conversation the user is a member of, with its mentions, reactions, star and
+// status.
+func getVisibleMessage(ctx context.Context, q querier, userID string, messageID string) (Message, error) {
+	message, err := scanMessage(q.QueryRowContext(ctx, messageSelect+" WHERE m.id = ?"+notExpired, messageID, now()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return message, fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+	} else if err != nil {
+		return message, err
+	}
+	if err := checkMember(ctx, q, message.ConversationID, userID); err != nil {
+		return message, err
+	}
+
+	messages := []Message{message}
+	if err := loadReactions(ctx, q, userID, messages); err != nil {
+		return message, err
+	}
+	if err := loadStars(ctx, q, userID, messages); err != nil {
+		return message, err
+	}
+	if err := loadMentions(ctx, q, messages); err != nil {
+		return message, err
+	}
+	err = loadStatuses(ctx, q, message.ConversationID, messages)
+	return messages[0], err
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// CreateGroupInvite creates an invite to a group the user is an admin of. The invite expires at expiresAt, unless it's
+// the zero time, and can be used maxUses times, or any number of times if maxUses is 0.
+func (db *appdbimpl) CreateGroupInvite(ctx context.Context, userID string, groupID string, expiresAt time.Time, maxUses int) (Invite, error) {
+	var invite Invite
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupAdmin(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		token, err := newInviteToken()
+		if err != nil {
+			return err
+		}
+		var expires sql.NullInt64
+		if !expiresAt.IsZero() {
+			expires = sql.NullInt64{Int64: expiresAt.UnixMilli(), Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO group_invites (token, group_id, created_by, created_at, expires_at, max_uses)
+			VALUES (?, ?, ?, ?, ?, NULLIF(?, 0))`, token, groupID, userID, now(), expires, maxUses)
+		if err != nil {
+			return err
+		}
+
+		invite, err = scanInvite(tx.QueryRowContext(ctx, inviteSelect+" WHERE token = ?", token))
+		return err
+	})
+	return invite, err
+}
+
+// newInviteToken returns a new random invite token. Unlike identifiers, tokens grant access: they have 128 random bits.
+func newInviteToken() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("generating invite token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf[:]), nil
+}
+
+// inviteSelect selects invites, to be scanned with scanInvite.
+const inviteSelect = `
+	SELECT token, group_id, created_by, created_at, expires_at, max_uses, uses, revoked_at IS NOT NULL
+	FROM group_invites`
+
+func scanInvite(row scanner) (Invite, error) {
+	var i Invite
+	var createdBy sql.NullString
+	var createdAt int64
+	var expiresAt, maxUses sql.NullInt64
+	err := row.Scan(&i.Token, &i.GroupID, &createdBy, &createdAt, &expiresAt, &maxUses, &i.Uses, &i.Revoked)
+	i.CreatedBy = createdBy.String
+	i.CreatedAt = fromMillis(createdAt)
+	if expiresAt.Valid {
+		t := fromMillis(expiresAt.Int64)
+		i.ExpiresAt = &t
+	}
+	i.MaxUses = int(maxUses.Int64)
+	return i, err
+}
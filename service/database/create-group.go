@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CreateGroup creates a new group named `name`, with the user as owner and memberIDs as the other members. Duplicated
+// identifiers (and the user itself) in memberIDs are ignored; ErrNotFound is returned if any of them does not exist.
+func (db *appdbimpl) CreateGroup(ctx context.Context, userID string, name string, memberIDs []string) (Group, error) {
+	groupID, err := newID()
+	if err != nil {
+		return Group{}, err
+	}
+
+	var group Group
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := insertConversation(ctx, tx, groupID, true); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO groups (id, name, created_by) VALUES (?, ?, ?)", groupID, name, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := addGroupMember(ctx, tx, groupID, userID, RoleOwner); err != nil {
+			return err
+		}
+		added := map[string]bool{userID: true}
+		for _, memberID := range memberIDs {
+			if added[memberID] {
+				continue
+			}
+			if err := addGroupMember(ctx, tx, groupID, memberID, RoleMember); err != nil {
+				return err
+			}
+			added[memberID] = true
+		}
+
+		group, err = getGroup(ctx, tx, groupID)
+		return err
+	})
+	return group, err
+}
+
+// getGroup returns a group with its members and their roles.
+func getGroup(ctx context.Context, q querier, groupID string) (Group, error) {
+	var group Group
+	var createdBy sql.NullString
+	err := q.QueryRowContext(ctx, "SELECT id, name, created_by FROM groups WHERE id = ?", groupID).
+		Scan(&group.ID, &group.Name, &createdBy)
+	if err != nil {
+		return group, err
+	}
+	group.CreatedBy = createdBy.String
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT u.id, u.name, gm.role
+		FROM conversation_members m
+		JOIN group_members gm ON gm.group_id = m.conversation_id AND gm.user_id = m.user_id
+		JOIN users u ON u.id = m.user_id
+		WHERE m.conversation_id = ?
+		ORDER BY m.joined_at, u.name`, groupID)
+	if err != nil {
+		return group, err
+	}
+	defer rows.Close()
+
+	group.Members = []GroupMember{}
+	for rows.Next() {
+		var m GroupMember
+		if err := rows.Scan(&m.ID, &m.Name, &m.Role); err != nil {
+			return group, err
+		}
+		group.Members = append(group.Members, m)
+	}
+	return group, rows.Err()
+}
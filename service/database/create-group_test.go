@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateGroup(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+
+	// Duplicates and the creator are ignored in the member list
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID, alice.ID, carol.ID, bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Name != "friends" || group.CreatedBy != alice.ID || len(group.Members) != 3 {
+		t.Fatalf("group = %+v, want friends created by alice with 3 members", group)
+	}
+	roles := map[string]string{}
+	for _, m := range group.Members {
+		roles[m.ID] = m.Role
+	}
+	if roles[alice.ID] != RoleOwner || roles[bob.ID] != RoleMember || roles[carol.ID] != RoleMember {
+		t.Errorf("roles = %v, want alice owner and the others members", roles)
+	}
+	if c, err := db.GetConversation(ctx, carol.ID, group.ID); err != nil || !c.IsGroup || c.Name != "friends" {
+		t.Errorf("conversation of a member = %+v, %v, want the group", c, err)
+	}
+
+	// Nothing is created if a member doesn't exist
+	if _, err := db.CreateGroup(ctx, alice.ID, "ghosts", []string{bob.ID, "nonexistent0"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("group with an unknown member = %v, want ErrNotFound", err)
+	}
+	conversations, _, err := db.GetConversations(ctx, bob.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 1 {
+		t.Errorf("bob is in %d conversations, want 1", len(conversations))
+	}
+}
@@ -2,8 +2,9 @@
 Package database is the middleware between the app database and the code. All data (de)serialization (save/load) from a
 persistent database are handled here. Database specific logic should never escape this package.
 
-To use this package you need to apply migrations to the database if needed/wanted, connect to it (using the database
-data source name from config), and then initialize an instance of AppDatabase from the DB connection.
+To use this package you need to connect to the database (using the database data source name from config), and then
+initialize an instance of AppDatabase from the DB connection. New applies any pending schema migration (embedded in the
+executable from the `migrations` directory, see migrate.go) before returning.
 
 For example, this code adds a parameter in `webapi` executable for the database data source name (add it to the
 main.WebAPIConfiguration structure):
@@ -12,11 +13,11 @@ main.WebAPIConfiguration structure):
 		Filename string `conf:""`
 	}
 
-This is an example on how to migrate the DB and connect to it:
+This is an example on how to connect to the DB (foreign keys must be enabled, as the schema relies on them):
 
 	// Start Database
 	logger.Println("initializing database support")
-	db, err := sql.Open("sqlite3", "./foo.db")
+	db, err := sql.Open("sqlite3", "./foo.db?_foreign_keys=on")
 	if err != nil {
 		logger.WithError(err).Error("error opening SQLite DB")
 		return fmt.Errorf("opening SQLite: %w", err)
@@ -27,55 +28,242 @@ This is an example on how to migrate the DB and connect to it:
 	}()
 
 Then you can initialize the AppDatabase and pass it to the api package.
+
+Message search is best with the FTS5 extension of SQLite, which go-sqlite3 includes only when built with the
+`sqlite_fts5` tag (e.g., `go build -tags sqlite_fts5 ./cmd/webapi`): messages are then matched by words, regardless of
+case and accents, and ranked. Without it, they are matched with LIKE, newest first. Once a database has been opened with
+FTS5, its index must be kept up to date: New returns ErrFTS5Unavailable if it's opened without FTS5 afterwards.
 */
 package database
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
 	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"sync"
+	"time"
+)
+
+// Errors returned by AppDatabase methods. They may be wrapped with details: use errors.Is to check them.
+var (
+	// ErrNotFound is returned when the requested entity (user, conversation, message, ...) does not exist.
+	ErrNotFound = errors.New("not found")
 
-	"github.com/PrinceLM1013/WasaText/service/api/reqcontext"
+	// ErrForbidden is returned when the user is not allowed to perform the operation (e.g., not a member of the
+	// conversation, or not the author of the message).
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrConflict is returned when the operation conflicts with the current state (e.g., the username is taken).
+	ErrConflict = errors.New("conflict")
 )
 
-// AppDatabase is the high level interface for the DB
-type AppDatabase interface {
-	GetName() (string, error)
-	SetName(name string) error
+// ErrFTS5Unavailable is returned by New when the database has a full-text index, but SQLite has been built without the
+// FTS5 extension.
+var ErrFTS5Unavailable = errors.New("SQLite FTS5 extension not available, but used by the database (build with `-tags sqlite_fts5`)")
 
+// AppDatabase is the high level interface for the DB. Every method operating on behalf of a user receives the
+// identifier of the authenticated user as userID, and checks that they are allowed to perform the operation.
+type AppDatabase interface {
 	Ping() error
 
-	// Session and User management
-	CreateUser(userName string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	Authorize(username string, token string, w http.ResponseWriter, ctx reqcontext.RequestContext) (is_valid bool)
-	ChangeUserName(newName string, username string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	UpPhoto(username string, fileBytes []byte, w http.ResponseWriter, ctx reqcontext.RequestContext)
-
-	// Conversations
-	GetMyConversations(username string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	GetConversation(username string, conversationID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-
-	// Messages
-	SendMessage(username string, content string, conversationID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	ForwardMessage(username string, messageID string, conversationID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	CommentMessage(username string, messageID string, comment string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	UncommentMessage(username string, messageID string, commentID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	DeleteMessage(username string, messageID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-
-	// Group management
-	LeaveGroup(username string, groupID string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	SetGroupPhoto(username string, groupID string, fileBytes []byte, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	AddToGroup(username string, groupID string, newMember string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-	SetGroupName(username string, groupID string, newName string, w http.ResponseWriter, ctx reqcontext.RequestContext)
-}
+	// GetOrCreateUser returns the user with the given name, creating it if needed.
+	GetOrCreateUser(ctx context.Context, name string) (User, error)
+
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id string) (User, error)
+
+	// SearchUsers returns a page of the users whose name starts with prefix, ignoring case, sorted by name and
+	// starting after the name after (from the first one if empty), and whether there are more past the page. The user
+	// is not included.
+	SearchUsers(ctx context.Context, userID string, prefix string, after string, limit int) ([]DirectoryUser, bool, error)
+
+	// UpdateUserName renames the user. ErrConflict is returned if the name is already taken.
+	UpdateUserName(ctx context.Context, userID string, name string) error
+
+	// SaveUserPhoto replaces the profile photo of the user with a registered blob.
+	SaveUserPhoto(ctx context.Context, userID string, photoKey string, mimeType string) error
+
+	// GetUserPhoto returns the profile photo of a user.
+	GetUserPhoto(ctx context.Context, userID string) (Photo, error)
+
+	// GetConversations returns a page of the conversations the user is a member of, most recently active first, and
+	// whether there are more past the page.
+	GetConversations(ctx context.Context, userID string, page Page) ([]Conversation, bool, error)
+
+	// GetConversation returns a single conversation of the user.
+	GetConversation(ctx context.Context, userID string, conversationID string) (Conversation, error)
+
+	// GetOrCreateDirectConversation returns the 1:1 conversation between the user and otherID, creating it if needed.
+	GetOrCreateDirectConversation(ctx context.Context, userID string, otherID string) (conversation Conversation, created bool, err error)
+
+	// GetMessages returns a page of the messages of a conversation the user is a member of, oldest first, and whether
+	// there are more past the page.
+	GetMessages(ctx context.Context, userID string, conversationID string, page Page) ([]Message, bool, error)
+
+	// GetConversationMembers returns the identifiers of the members of a conversation, without permission checks.
+	GetConversationMembers(ctx context.Context, conversationID string) ([]string, error)
+
+	// GetMessage returns a message of a conversation the user is a member of.
+	GetMessage(ctx context.Context, userID string, messageID string) (Message, error)
+
+	// SaveMessage sends a new message to a conversation the user is a member of, optionally replying to another
+	// message of the conversation and with an attachment.
+	SaveMessage(ctx context.Context, userID string, m NewMessage) (Message, error)
+
+	// ScheduleMessage stores a message to be sent by the user at sendAt, with the same checks as SaveMessage. Until
+	// then, only the user can see it.
+	ScheduleMessage(ctx context.Context, userID string, m NewMessage, sendAt time.Time) (ScheduledMessage, error)
+
+	// GetScheduledMessages returns the messages scheduled by the user and not sent yet (including the ones that failed),
+	// the first to be sent first.
+	GetScheduledMessages(ctx context.Context, userID string) ([]ScheduledMessage, error)
+
+	// DeleteScheduledMessage cancels a message scheduled by the user and not sent yet.
+	DeleteScheduledMessage(ctx context.Context, userID string, scheduledID string) error
+
+	// SendScheduledMessages sends a batch of the scheduled messages due at the given time, returning them as sent and
+	// the number of those discarded because their sender left the conversation. The messages that can't be sent are
+	// marked as failed (and not tried again), without stopping the others: their errors are returned at the end.
+	SendScheduledMessages(ctx context.Context, at time.Time) (sent []Message, discarded int, err error)
+
+	// GetAttachment returns an attachment of a message visible to the user. Its content is the blob keyed by its
+	// checksum.
+	GetAttachment(ctx context.Context, userID string, attachmentID string) (Attachment, error)
+
+	// ForwardMessage copies a message visible to the user into another conversation of the user.
+	ForwardMessage(ctx context.Context, userID string, messageID string, toConversationID string) (Message, error)
+
+	// EditMessage replaces the content of a message sent by the user, keeping the previous one in its history. Messages
+	// sent more than window ago can't be edited (no limit if window is 0).
+	EditMessage(ctx context.Context, userID string, messageID string, content string, window time.Duration) (Message, error)
+
+	// GetMessageHistory returns all the revisions of a message visible to the user, oldest first.
+	GetMessageHistory(ctx context.Context, userID string, messageID string) ([]Revision, error)
+
+	// SearchMessages returns the messages matching a search, among those of the conversations the user is a member
+	// of, best matches first, and whether there are more past the page.
+	SearchMessages(ctx context.Context, userID string, search Search) ([]SearchResult, bool, error)
+
+	// DeleteMessage deletes a message sent by the user.
+	DeleteMessage(ctx context.Context, userID string, messageID string) error
+
+	// SetMessageTimer sets the timer of disappearing messages of a conversation the user is a member of (an admin of,
+	// for groups), returning the system message recording it (empty if the timer didn't change).
+	SetMessageTimer(ctx context.Context, userID string, conversationID string, timer string) (Message, error)
+
+	// DeleteExpiredMessages deletes for good a batch of the messages expired at the given time, and returns them.
+	DeleteExpiredMessages(ctx context.Context, at time.Time) ([]ExpiredMessage, error)
+
+	// PinMessage pins a message in its conversation, if the user is allowed to (members in 1:1 conversations, admins
+	// in groups), returning it and whether it wasn't pinned yet. A conversation has at most MaxPinnedMessages pins.
+	PinMessage(ctx context.Context, userID string, messageID string) (message Message, changed bool, err error)
+
+	// UnpinMessage unpins a message, if the user is allowed to pin it, returning it.
+	UnpinMessage(ctx context.Context, userID string, messageID string) (Message, error)
+
+	// GetPinnedMessages returns the messages pinned in a conversation the user is a member of, the last pinned first.
+	GetPinnedMessages(ctx context.Context, userID string, conversationID string) ([]Pin, error)
 
-var writeErr = "error writing response"
-var rowErr = `{"error": "Failed to scan row", "ERR": "`
+	// StarMessage stars a message visible to the user, for the user only.
+	StarMessage(ctx context.Context, userID string, messageID string) error
+
+	// UnstarMessage removes the star of the user from a message.
+	UnstarMessage(ctx context.Context, userID string, messageID string) error
+
+	// GetStarredMessages returns a page of the messages starred by the user in their conversations, most recently
+	// starred first, and whether there are more past the page.
+	GetStarredMessages(ctx context.Context, userID string, page Page) ([]StarredMessage, bool, error)
+
+	// AddReaction adds a reaction of the user to a message with an emoji.
+	AddReaction(ctx context.Context, userID string, messageID string, emoji string) (Reaction, error)
+
+	// RemoveReaction removes the reaction of the user to a message with an emoji.
+	RemoveReaction(ctx context.Context, userID string, messageID string, emoji string) error
+
+	// MarkReceived records that the user has received the messages of a conversation sent up to the given time.
+	MarkReceived(ctx context.Context, userID string, conversationID string, upTo time.Time) (receipt Receipt, changed bool, err error)
+
+	// MarkRead records that the user has read the messages of a conversation up to the given message (all of them if
+	// messageID is empty).
+	MarkRead(ctx context.Context, userID string, conversationID string, messageID string) (receipt Receipt, changed bool, err error)
+
+	// CreateGroup creates a new group with the user as owner.
+	CreateGroup(ctx context.Context, userID string, name string, memberIDs []string) (Group, error)
+
+	// AddUserToGroup adds newMemberID to a group the user is an admin of, returning the system message recording it.
+	AddUserToGroup(ctx context.Context, userID string, groupID string, newMemberID string) (Message, error)
+
+	// PromoteGroupMember raises the role of memberID in a group, returning the new role.
+	PromoteGroupMember(ctx context.Context, userID string, groupID string, memberID string) (string, error)
+
+	// DemoteGroupMember lowers the role of memberID in a group, returning the new role.
+	DemoteGroupMember(ctx context.Context, userID string, groupID string, memberID string) (string, error)
+
+	// RemoveGroupMember removes memberID from a group the user is an admin of, returning the system message recording
+	// it.
+	RemoveGroupMember(ctx context.Context, userID string, groupID string, memberID string) (Message, error)
+
+	// CreateGroupInvite creates an invite to a group the user is an admin of, expiring at expiresAt (never if zero)
+	// and usable maxUses times (unlimited if 0).
+	CreateGroupInvite(ctx context.Context, userID string, groupID string, expiresAt time.Time, maxUses int) (Invite, error)
+
+	// GetGroupInvites returns the invites of a group the user is an admin of, newest first.
+	GetGroupInvites(ctx context.Context, userID string, groupID string) ([]Invite, error)
+
+	// RevokeGroupInvite revokes an invite to a group the user is an admin of.
+	RevokeGroupInvite(ctx context.Context, userID string, groupID string, token string) error
+
+	// AcceptGroupInvite adds the user to the group of an invite, returning the group and the system message recording
+	// the join.
+	AcceptGroupInvite(ctx context.Context, userID string, token string) (Group, Message, error)
+
+	// LeaveGroup removes the user from a group, returning the member who became owner in their place, if any, and the
+	// system message recording it (empty if the group has been deleted).
+	LeaveGroup(ctx context.Context, userID string, groupID string) (newOwnerID string, message Message, err error)
+
+	// UpdateGroupName renames a group the user is an admin of, returning the system message recording it (empty if
+	// the name didn't change).
+	UpdateGroupName(ctx context.Context, userID string, groupID string, name string) (Message, error)
+
+	// SaveGroupPhoto replaces the photo of a group the user is an admin of with a registered blob, returning the system
+	// message recording it.
+	SaveGroupPhoto(ctx context.Context, userID string, groupID string, photoKey string, mimeType string) (Message, error)
+
+	// GetGroupPhoto returns the photo of a group the user is a member of.
+	GetGroupPhoto(ctx context.Context, userID string, groupID string) (Photo, error)
+
+	// RegisterBlob records a blob about to be stored, before it's referenced by an attachment or a photo. Registering
+	// an existing blob does nothing, except postponing its collection if it's not referenced.
+	RegisterBlob(ctx context.Context, key string, size int64) error
+
+	// SaveThumbnails records the thumbnails of the image blob key. The thumbnails must have been registered as blobs.
+	// Thumbnails already recorded for the same size are kept.
+	SaveThumbnails(ctx context.Context, key string, thumbnails []Thumbnail) error
+
+	// GetThumbnail returns the thumbnail of the image blob key for the given size.
+	GetThumbnail(ctx context.Context, key string, size int) (Thumbnail, error)
+
+	// CollectBlobs forgets the blobs not referenced anymore since before releasedBefore, calling remove to delete each
+	// of them from the store (outside of any transaction). remove must succeed for blobs already gone from the store. It
+	// returns the number of blobs collected.
+	CollectBlobs(ctx context.Context, releasedBefore time.Time, remove func(key string) error) (int, error)
+
+	// MoveLegacyBlobs moves the content still saved in the database by older versions to the blob store, calling put
+	// to store each blob. It returns the number of blobs moved.
+	MoveLegacyBlobs(ctx context.Context, put func(ctx context.Context, key string, r io.Reader, size int64) error) (int, error)
+}
 
 type appdbimpl struct {
 	c *sql.DB
+
+	// Whether messages are searched with the full-text index
+	fts bool
+
+	// The blobs being removed from the store by CollectBlobs, with a channel closed once they are gone
+	collectingMu sync.Mutex
+	collecting   map[string]chan struct{}
 }
 
 // New returns a new instance of AppDatabase based on the SQLite connection `db`.
@@ -85,22 +273,26 @@ func New(db *sql.DB) (AppDatabase, error) {
 		return nil, errors.New("database is required when building a AppDatabase")
 	}
 
-	// Check if table exists. If not, the database is empty, and we need to create the structure
-	var tableName string
-	err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='example_table';`).Scan(&tableName)
-	if errors.Is(err, sql.ErrNoRows) {
-		sqlStmt := `CREATE TABLE example_table (id INTEGER NOT NULL PRIMARY KEY, name TEXT);`
-		_, err = db.Exec(sqlStmt)
-		if err != nil {
-			return nil, fmt.Errorf("error creating database structure: %w", err)
-		}
+	// The full-text index needs FTS5, which is optional in SQLite builds
+	fts5, err := hasFTS5(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bring the database structure to the latest version
+	if err := migrate(db, fts5); err != nil {
+		return nil, fmt.Errorf("error migrating database structure: %w", err)
+	}
+
+	fts, err := setupSearch(db, fts5)
+	if err != nil {
+		return nil, err
 	}
-	query := `
-			CREATE TABLE IF NOT EXISTS user
-	`
 
 	return &appdbimpl{
-		c: db,
+		c:          db,
+		fts:        fts,
+		collecting: map[string]chan struct{}{},
 	}, nil
 }
 
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PrinceLM1013/WasaText/service/globaltime"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestConn opens a new, empty SQLite database, closed at the end of the test.
+func openTestConn(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "wasatext.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// openTestDB returns a new database, migrated to the latest version, which is removed at the end of the test.
+func openTestDB(t *testing.T) *appdbimpl {
+	t.Helper()
+	db, err := New(openTestConn(t))
+	if err != nil {
+		t.Fatalf("creating AppDatabase: %v", err)
+	}
+	return db.(*appdbimpl)
+}
+
+// createUser creates a user named name.
+func createUser(t *testing.T, db AppDatabase, name string) User {
+	t.Helper()
+	u, err := db.GetOrCreateUser(context.Background(), name)
+	if err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	return u
+}
+
+// startConversation returns the 1:1 conversation between two users.
+func startConversation(t *testing.T, db AppDatabase, userID string, otherID string) Conversation {
+	t.Helper()
+	c, _, err := db.GetOrCreateDirectConversation(context.Background(), userID, otherID)
+	if err != nil {
+		t.Fatalf("starting conversation: %v", err)
+	}
+	return c
+}
+
+// sendText sends a text message to a conversation.
+func sendText(t *testing.T, db AppDatabase, userID string, conversationID string, content string) Message {
+	t.Helper()
+	m, err := db.SaveMessage(context.Background(), userID, NewMessage{ConversationID: conversationID, Content: content})
+	if err != nil {
+		t.Fatalf("sending %q: %v", content, err)
+	}
+	return m
+}
+
+// setTime fixes the current time of the database to at, until the end of the test.
+func setTime(t *testing.T, at time.Time) {
+	t.Helper()
+	globaltime.FixedTime = at
+	t.Cleanup(func() {
+		globaltime.FixedTime = time.Time{}
+	})
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// expireBatch is the maximum number of messages deleted by a single call of DeleteExpiredMessages.
+const expireBatch = 100
+
+// DeleteExpiredMessages deletes for good the messages expired at the given time, oldest first, and returns them. Their
+// attachments, reactions, revisions and search entries are deleted with them (the content of the attachments is
+// released, to be collected with the other unreferenced blobs); the replies to them lose their quote. Messages still
+// unread are no longer counted as such, and the last message of their conversation is replaced by the previous one.
+func (db *appdbimpl) DeleteExpiredMessages(ctx context.Context, at time.Time) ([]ExpiredMessage, error) {
+	var expired []ExpiredMessage
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, conversation_id, sender_id, created_at, deleted_at IS NOT NULL FROM messages
+			WHERE expires_at <= ? ORDER BY expires_at LIMIT ?`, at.UnixMilli(), expireBatch)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		type expiring struct {
+			ExpiredMessage
+			senderID  string
+			createdAt int64
+			deleted   bool
+		}
+		var messages []expiring
+		for rows.Next() {
+			var m expiring
+			if err := rows.Scan(&m.ID, &m.ConversationID, &m.senderID, &m.createdAt, &m.deleted); err != nil {
+				return err
+			}
+			messages = append(messages, m)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		_ = rows.Close()
+
+		conversations := map[string]bool{}
+		for _, m := range messages {
+			// Deleted messages aren't counted as unread anymore
+			if !m.deleted {
+				_, err := tx.ExecContext(ctx, `
+					UPDATE conversation_members SET unread_count = unread_count - 1
+					WHERE conversation_id = ? AND user_id != ? AND joined_at <= ? AND read_upto < ? AND unread_count > 0`,
+					m.ConversationID, m.senderID, m.createdAt, m.createdAt)
+				if err != nil {
+					return err
+				}
+				if err := countMentions(ctx, tx, m.ConversationID, m.ID, m.createdAt, -1); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM messages WHERE id = ?", m.ID); err != nil {
+				return err
+			}
+			conversations[m.ConversationID] = true
+			expired = append(expired, m.ExpiredMessage)
+		}
+
+		// The last message of a conversation is unset when it's deleted
+		for conversationID := range conversations {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE conversations SET last_message_id = (
+					SELECT id FROM messages WHERE conversation_id = conversations.id AND kind = ?
+					ORDER BY created_at DESC, id DESC LIMIT 1
+				)
+				WHERE id = ? AND last_message_id IS NULL`, MessageText, conversationID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return expired, err
+}
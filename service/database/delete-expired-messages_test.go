@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetMessageTimer(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob, eve := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "eve")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the admins of a group can set it
+	if _, err := db.SetMessageTimer(ctx, bob.ID, group.ID, TimerHour); !errors.Is(err, ErrForbidden) {
+		t.Errorf("timer set by a plain member = %v, want ErrForbidden", err)
+	}
+	if _, err := db.SetMessageTimer(ctx, eve.ID, group.ID, TimerHour); !errors.Is(err, ErrForbidden) {
+		t.Errorf("timer set by a non-member = %v, want ErrForbidden", err)
+	}
+	if _, err := db.SetMessageTimer(ctx, alice.ID, "nonexistent0", TimerHour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("timer of an unknown conversation = %v, want ErrNotFound", err)
+	}
+
+	m, err := db.SetMessageTimer(ctx, alice.ID, group.ID, TimerHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.System == nil || m.System.Action != ActionTimerChanged || m.System.OldValue != TimerOff || m.System.NewValue != TimerHour {
+		t.Errorf("timer message = %+v, want the timer changed from off to 1h", m.System)
+	}
+	if c, err := db.GetConversation(ctx, bob.ID, group.ID); err != nil || c.MessageTimer != TimerHour {
+		t.Errorf("conversation timer = %q, %v, want 1h", c.MessageTimer, err)
+	}
+	if m, err := db.SetMessageTimer(ctx, alice.ID, group.ID, TimerHour); err != nil || m.ID != "" {
+		t.Errorf("setting the same timer = %+v, %v, want no message", m, err)
+	}
+
+	// The messages sent from now on expire
+	setTime(t, start.Add(time.Minute))
+	if m := sendText(t, db, bob.ID, group.ID, "hi"); m.ExpiresAt == nil || !m.ExpiresAt.Equal(start.Add(time.Minute+time.Hour)) {
+		t.Errorf("message expires at %v, want %v", m.ExpiresAt, start.Add(time.Minute+time.Hour))
+	}
+
+	// Both members of a 1:1 conversation can set it
+	c := startConversation(t, db, alice.ID, eve.ID)
+	if _, err := db.SetMessageTimer(ctx, eve.ID, c.ID, TimerDay); err != nil {
+		t.Errorf("timer set in a 1:1 conversation: %v", err)
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob := createUser(t, db, "alice"), createUser(t, db, "bob")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	sendText(t, db, alice.ID, c.ID, "kept")
+	if _, err := db.SetMessageTimer(ctx, alice.ID, c.ID, TimerHour); err != nil {
+		t.Fatal(err)
+	}
+	gone := sendText(t, db, alice.ID, c.ID, "gone")
+	setTime(t, start.Add(30*time.Minute))
+	reply, err := db.SaveMessage(ctx, bob.ID, NewMessage{ConversationID: c.ID, Content: "why?", ReplyTo: gone.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unread, _ := unreadCounts(t, db, bob.ID, c.ID)
+
+	// Expired messages are hidden right away, before they are deleted
+	setTime(t, start.Add(time.Hour))
+	messages, _, err := db.GetMessages(ctx, bob.ID, c.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range messages {
+		if m.ID == gone.ID {
+			t.Errorf("expired message listed: %+v", m)
+		} else if m.ID == reply.ID && m.ReplyTo != nil {
+			t.Errorf("reply quoting the expired message: %+v", m.ReplyTo)
+		}
+	}
+	if _, err := db.GetMessage(ctx, bob.ID, gone.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMessage of an expired message = %v, want ErrNotFound", err)
+	}
+	if results, _, err := db.SearchMessages(ctx, bob.ID, Search{Text: "gone", Limit: 10}); err != nil || len(results) != 0 {
+		t.Errorf("search of an expired message = %v, %v, want nothing", results, err)
+	}
+	_, err = db.SaveMessage(ctx, bob.ID, NewMessage{ConversationID: c.ID, Content: "?", ReplyTo: gone.ID})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("reply to an expired message = %v, want ErrNotFound", err)
+	}
+
+	// The messages expired at the given time are deleted, whatever the current time
+	setTime(t, start.Add(2*time.Hour))
+	expired, err := db.DeleteExpiredMessages(ctx, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].ID != gone.ID || expired[0].ConversationID != c.ID {
+		t.Errorf("expired messages = %+v, want %s", expired, gone.ID)
+	}
+	if got, _ := unreadCounts(t, db, bob.ID, c.ID); got != unread-1 {
+		t.Errorf("unread messages after the deletion = %d, want %d", got, unread-1)
+	}
+
+	// The last message expires too: the preview falls back to the previous message once it's deleted
+	if conversation, err := db.GetConversation(ctx, alice.ID, c.ID); err != nil || conversation.LastMessage != "" {
+		t.Errorf("preview of an expired message = %q, %v, want none", conversation.LastMessage, err)
+	}
+	expired, err = db.DeleteExpiredMessages(ctx, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].ID != reply.ID {
+		t.Errorf("expired messages = %+v, want %s", expired, reply.ID)
+	}
+	if conversation, err := db.GetConversation(ctx, alice.ID, c.ID); err != nil || conversation.LastMessage != "kept" {
+		t.Errorf("preview after the deletion = %q, %v, want kept", conversation.LastMessage, err)
+	}
+	if expired, err := db.DeleteExpiredMessages(ctx, start.Add(2*time.Hour)); err != nil || len(expired) != 0 {
+		t.Errorf("deleting again = %+v, %v, want nothing", expired, err)
+	}
+}
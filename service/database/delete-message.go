@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DeleteMessage deletes a message sent by the user. The message is kept as a placeholder without content, so that the
+// conversation history stays consistent; its attachment, mentions, reactions, previous revisions, pin and stars are
+// removed, and it's no longer counted as unread.
+func (db *appdbimpl) DeleteMessage(ctx context.Context, userID string, messageID string) error {
+	return db.withTx(ctx, func(tx *sql.Tx) error {
+		message, err := getVisibleMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if message.Deleted {
+			return fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		} else if message.Kind == MessageSystem {
+			return fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+		} else if message.SenderID != userID {
+			return fmt.Errorf("message %s sent by another user: %w", messageID, ErrForbidden)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE messages SET content = '', deleted_at = ? WHERE id = ?", now(), messageID); err != nil {
+			return err
+		}
+		if err := countMentions(ctx, tx, message.ConversationID, messageID, message.Timestamp.UnixMilli(), -1); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM message_mentions WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE conversation_members SET unread_count = unread_count - 1
+			WHERE conversation_id = ? AND user_id != ? AND joined_at <= ? AND read_upto < ? AND unread_count > 0`,
+			message.ConversationID, userID, message.Timestamp.UnixMilli(), message.Timestamp.UnixMilli())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM attachments WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM message_revisions WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM reactions WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pinned_messages WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM starred_messages WHERE message_id = ?", messageID)
+		return err
+	})
+}
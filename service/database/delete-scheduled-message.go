@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeleteScheduledMessage cancels a message scheduled by the user. ErrNotFound is returned if there is no such message
+// (possibly because it has already been sent).
+func (db *appdbimpl) DeleteScheduledMessage(ctx context.Context, userID string, scheduledID string) error {
+	res, err := db.c.ExecContext(ctx, "DELETE FROM scheduled_messages WHERE id = ? AND sender_id = ?", scheduledID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("scheduled message %s: %w", scheduledID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DemoteGroupMember lowers the role of memberID in a group by one step (owner to admin, admin to member), and returns
+// the new role. Members can be demoted by those with a higher role, or by an owner, and anyone can demote themselves;
+// the last owner of a group can't be demoted (ErrConflict), as can't be plain members.
+func (db *appdbimpl) DemoteGroupMember(ctx context.Context, userID string, groupID string, memberID string) (string, error) {
+	var newRole string
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		role, memberRole, err := memberRoles(ctx, tx, groupID, userID, memberID)
+		if err != nil {
+			return err
+		}
+
+		switch memberRole {
+		case RoleOwner:
+			newRole = RoleAdmin
+		case RoleAdmin:
+			newRole = RoleMember
+		default:
+			return fmt.Errorf("user %s is already a plain member: %w", memberID, ErrConflict)
+		}
+		if memberID != userID && role != RoleOwner && roleRank(role) <= roleRank(memberRole) {
+			return fmt.Errorf("not allowed to demote members with role %s: %w", memberRole, ErrForbidden)
+		}
+
+		if memberRole == RoleOwner {
+			var owners int
+			err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM group_members WHERE group_id = ? AND role = ?",
+				groupID, RoleOwner).Scan(&owners)
+			if err != nil {
+				return err
+			} else if owners == 1 {
+				return fmt.Errorf("group %s must have an owner: %w", groupID, ErrConflict)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?",
+			newRole, groupID, memberID)
+		return err
+	})
+	return newRole, err
+}
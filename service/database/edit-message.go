@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// EditMessage replaces the content of a message sent by the user. The previous content is kept as a revision (see
+// GetMessageHistory). Once window has passed since the message was sent, ErrForbidden is returned; a zero window means
+// no limit. Editing a message with its current content does nothing.
+func (db *appdbimpl) EditMessage(ctx context.Context, userID string, messageID string, content string, window time.Duration) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		message, err = getVisibleMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if message.Deleted {
+			return fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		} else if message.Kind == MessageSystem {
+			return fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+		} else if message.SenderID != userID {
+			return fmt.Errorf("message %s sent by another user: %w", messageID, ErrForbidden)
+		}
+
+		editedAt := now()
+		if window > 0 && editedAt-message.Timestamp.UnixMilli() > window.Milliseconds() {
+			return fmt.Errorf("message %s can no longer be edited: %w", messageID, ErrForbidden)
+		}
+		if content == message.Content {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO message_revisions (message_id, revision, content, created_at)
+			SELECT m.id, (SELECT COUNT(*) + 1 FROM message_revisions WHERE message_id = m.id), m.content,
+				COALESCE(m.edited_at, m.created_at)
+			FROM messages m WHERE m.id = ?`, messageID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE messages SET content = ?, edited_at = ? WHERE id = ?", content, editedAt, messageID)
+		if err != nil {
+			return err
+		}
+
+		// The mentions are found again in the new content
+		createdAt := message.Timestamp.UnixMilli()
+		if err := countMentions(ctx, tx, message.ConversationID, messageID, createdAt, -1); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM message_mentions WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		if err := saveMentions(ctx, tx, message.ConversationID, userID, messageID, content); err != nil {
+			return err
+		}
+		if err := countMentions(ctx, tx, message.ConversationID, messageID, createdAt, 1); err != nil {
+			return err
+		}
+
+		message, err = getVisibleMessage(ctx, tx, userID, messageID)
+		return err
+	})
+	return message, err
+}
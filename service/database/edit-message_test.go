@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEditMessage(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob := createUser(t, db, "alice"), createUser(t, db, "bob")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	m := sendText(t, db, alice.ID, c.ID, "helo")
+
+	const window = 15 * time.Minute
+	setTime(t, start.Add(time.Minute))
+	edited, err := db.EditMessage(ctx, alice.ID, m.ID, "hello", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Content != "hello" || edited.EditedAt == nil || !edited.EditedAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("edited message = %q edited at %v, want hello at %v", edited.Content, edited.EditedAt, start.Add(time.Minute))
+	}
+	setTime(t, start.Add(2*time.Minute))
+	if _, err := db.EditMessage(ctx, alice.ID, m.ID, "hello!", window); err != nil {
+		t.Fatal(err)
+	}
+	// The same content again is not a revision
+	if _, err := db.EditMessage(ctx, alice.ID, m.ID, "hello!", window); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every member sees the history, oldest first, with the time each content was written
+	history, err := db.GetMessageHistory(ctx, bob.ID, m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Revision{
+		{Content: "helo", Timestamp: start},
+		{Content: "hello", Timestamp: start.Add(time.Minute)},
+		{Content: "hello!", Timestamp: start.Add(2 * time.Minute)},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history = %v, want %v", history, want)
+	}
+	for i := range want {
+		if history[i].Content != want[i].Content || !history[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("revision %d = %v, want %v", i, history[i], want[i])
+		}
+	}
+
+	// Search finds the current content only
+	results, _, err := db.SearchMessages(ctx, bob.ID, Search{Text: "helo", Limit: 10})
+	if err != nil || len(results) != 0 {
+		t.Errorf("search of the old content = %v, %v, want nothing", results, err)
+	}
+
+	// Only the sender, within the window
+	if _, err := db.EditMessage(ctx, bob.ID, m.ID, "hijacked", window); !errors.Is(err, ErrForbidden) {
+		t.Errorf("edit by another member = %v, want ErrForbidden", err)
+	}
+	eve := createUser(t, db, "eve")
+	if _, err := db.EditMessage(ctx, eve.ID, m.ID, "hijacked", window); !errors.Is(err, ErrForbidden) && !errors.Is(err, ErrNotFound) {
+		t.Errorf("edit by a non-member = %v, want ErrForbidden or ErrNotFound", err)
+	}
+	setTime(t, start.Add(window+time.Second))
+	if _, err := db.EditMessage(ctx, alice.ID, m.ID, "too late", window); !errors.Is(err, ErrForbidden) {
+		t.Errorf("edit after the window = %v, want ErrForbidden", err)
+	}
+	if _, err := db.EditMessage(ctx, alice.ID, m.ID, "no window", 0); err != nil {
+		t.Errorf("edit without a window = %v", err)
+	}
+
+	// Deleted messages can't be edited, and lose their history
+	if err := db.DeleteMessage(ctx, alice.ID, m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.EditMessage(ctx, alice.ID, m.ID, "back", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("edit of a deleted message = %v, want ErrNotFound", err)
+	}
+	if _, err := db.GetMessageHistory(ctx, alice.ID, m.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("history of a deleted message = %v, want ErrNotFound", err)
+	}
+}
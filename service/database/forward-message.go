@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ForwardMessage copies a message of a conversation the user is a member of into another conversation of the user. The
+// copy, with the attachment, is sent by the user and marked as forwarded; it does not carry the reply, which would
+// point to another conversation. The attachment of the copy refers to the same blob as the original.
+func (db *appdbimpl) ForwardMessage(ctx context.Context, userID string, messageID string, toConversationID string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		original, err := getVisibleMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if original.Deleted {
+			return fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		} else if original.Kind == MessageSystem {
+			return fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+		}
+
+		if err := checkMember(ctx, tx, toConversationID, userID); err != nil {
+			return err
+		}
+
+		forward := NewMessage{ConversationID: toConversationID, Content: original.Content, Attachment: original.Attachment}
+
+		message, err = insertMessage(ctx, tx, userID, forward, true)
+		return err
+	})
+	return message, err
+}
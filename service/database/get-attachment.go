@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetAttachment returns an attachment of a message visible to the user. Its content is the blob keyed by its checksum.
+// Attachments of deleted messages are not available anymore (ErrNotFound).
+func (db *appdbimpl) GetAttachment(ctx context.Context, userID string, attachmentID string) (Attachment, error) {
+	var messageID string
+	err := db.c.QueryRowContext(ctx, "SELECT message_id FROM attachments WHERE id = ?", attachmentID).Scan(&messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attachment{}, fmt.Errorf("attachment %s: %w", attachmentID, ErrNotFound)
+	} else if err != nil {
+		return Attachment{}, err
+	}
+
+	message, err := getVisibleMessage(ctx, db.c, userID, messageID)
+	if err != nil {
+		return Attachment{}, err
+	} else if message.Deleted || message.Attachment == nil {
+		return Attachment{}, fmt.Errorf("attachment %s: %w", attachmentID, ErrNotFound)
+	}
+	return *message.Attachment, nil
+}
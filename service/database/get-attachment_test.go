@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetAttachment(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, eve := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "eve")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	if err := db.RegisterBlob(ctx, "photo", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	attachment := Attachment{FileName: "cat.png", MIMEType: "image/png", Size: 2048, Width: 640, Height: 480, Checksum: "photo"}
+	m, err := db.SaveMessage(ctx, alice.ID, NewMessage{ConversationID: c.ID, Content: "look", Attachment: &attachment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Attachment == nil || m.Attachment.ID == "" {
+		t.Fatalf("attachment = %+v, want it stored", m.Attachment)
+	}
+	attachment.ID = m.Attachment.ID
+	if *m.Attachment != attachment {
+		t.Errorf("attachment = %+v, want %+v", *m.Attachment, attachment)
+	}
+	if got, err := db.GetAttachment(ctx, bob.ID, attachment.ID); err != nil || got != attachment {
+		t.Errorf("GetAttachment = %+v, %v, want %+v", got, err, attachment)
+	}
+
+	// Only the members get it, until the message is deleted
+	if _, err := db.GetAttachment(ctx, eve.ID, attachment.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("attachment of the others = %v, want ErrForbidden", err)
+	}
+	if _, err := db.GetAttachment(ctx, bob.ID, "nonexistent0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown attachment = %v, want ErrNotFound", err)
+	}
+	if err := db.DeleteMessage(ctx, alice.ID, m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetAttachment(ctx, bob.ID, attachment.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("attachment of a deleted message = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+)
+
+// GetConversationMembers returns the identifiers of the members of a conversation. It performs no permission check, as
+// it is meant for internal use (e.g., to find who should be notified of a change).
+func (db *appdbimpl) GetConversationMembers(ctx context.Context, conversationID string) ([]string, error) {
+	rows, err := db.c.QueryContext(ctx, "SELECT user_id FROM conversation_members WHERE conversation_id = ?", conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		members = append(members, userID)
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,13 @@
+package database
+
+import (
+	"context"
+)
+
+// GetConversation returns a conversation the user is a member of.
+func (db *appdbimpl) GetConversation(ctx context.Context, userID string, conversationID string) (Conversation, error) {
+	if err := checkMember(ctx, db.c, conversationID, userID); err != nil {
+		return Conversation{}, err
+	}
+	return scanConversation(db.c.QueryRowContext(ctx, conversationSelect+" AND c.id = ?", userID, conversationID))
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// conversationSelect selects the conversations of the user passed as first parameter, to be scanned with
+// scanConversation. The name of a 1:1 conversation is the name of the other participant.
+const conversationSelect = conversationColumns + conversationFrom
+
+// conversationColumns and conversationFrom are the parts of conversationSelect, for queries selecting more columns.
+const conversationColumns = `
+	SELECT c.id, c.is_group, COALESCE(g.name, u.name, ''), c.last_activity_at, m.unread_count, m.mention_count,
+		lm.content, lm.deleted_at IS NOT NULL, ls.name, la.mime_type, c.message_timer, lm.expires_at`
+
+const conversationFrom = `
+	FROM conversation_members m
+	JOIN conversations c ON c.id = m.conversation_id
+	LEFT JOIN groups g ON g.id = c.id
+	LEFT JOIN conversation_members o ON c.is_group = 0 AND o.conversation_id = c.id AND o.user_id != m.user_id
+	LEFT JOIN users u ON u.id = o.user_id
+	LEFT JOIN messages lm ON lm.id = c.last_message_id
+	LEFT JOIN users ls ON ls.id = lm.sender_id
+	LEFT JOIN attachments la ON la.message_id = lm.id
+	WHERE m.user_id = ?`
+
+// Previews of the last message of a conversation.
+const (
+	// previewLength is the maximum length of a preview, in characters.
+	previewLength = 100
+
+	// previewDeleted replaces the content of deleted messages.
+	previewDeleted = "deleted"
+
+	// previewPhoto and previewFile replace the content of messages with an attachment and no text.
+	previewPhoto = "Photo"
+	previewFile  = "File"
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scannerFunc adapts a function to the scanner interface, e.g. to scan extra columns.
+type scannerFunc func(dest ...interface{}) error
+
+func (f scannerFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+func scanConversation(row scanner) (Conversation, error) {
+	var c Conversation
+	var lastActivity int64
+	var lastContent, lastSender, lastMIMEType sql.NullString
+	var lastDeleted sql.NullBool
+	var lastExpiresAt sql.NullInt64
+	err := row.Scan(&c.ID, &c.IsGroup, &c.Name, &lastActivity, &c.UnreadCount, &c.MentionCount, &lastContent, &lastDeleted, &lastSender,
+		&lastMIMEType, &c.MessageTimer, &lastExpiresAt)
+	c.Timestamp = fromMillis(lastActivity)
+	// An expired last message is not shown, until it's deleted and replaced by the previous one
+	if !lastExpiresAt.Valid || lastExpiresAt.Int64 > now() {
+		c.LastMessage = preview(lastContent.String, lastDeleted.Bool, lastMIMEType.String)
+		c.LastMessageSender = lastSender.String
+	}
+	return c, err
+}
+
+// preview returns the text shown in the conversation list for a message. mimeType is the type of its attachment, if
+// any.
+func preview(content string, deleted bool, mimeType string) string {
+	if deleted {
+		return previewDeleted
+	}
+	if content == "" && strings.HasPrefix(mimeType, "image/") {
+		return previewPhoto
+	} else if content == "" && mimeType != "" {
+		return previewFile
+	}
+	if runes := []rune(content); len(runes) > previewLength {
+		return string(runes[:previewLength-1]) + "…"
+	}
+	return content
+}
+
+// GetConversations returns a page of the conversations the user is a member of, most recently active first regardless
+// of the direction of the page. more reports whether there are other conversations past the page, in its direction.
+// With a snapshot in page.Before, the conversations are sorted by their activity as of the snapshot, and those created
+// or joined after it are left out (see Cursor).
+func (db *appdbimpl) GetConversations(ctx context.Context, userID string, page Page) (conversations []Conversation, more bool, err error) {
+	// The activity as of the snapshot is computed as last_activity_at is maintained, from the text messages. The
+	// snapshot is inlined, being an integer: the expression appears several times in the query.
+	activity := "c.last_activity_at"
+	var snapshot string
+	if page.Before != nil && !page.Before.Snapshot.IsZero() {
+		at := strconv.FormatInt(page.Before.Snapshot.UnixMilli(), 10)
+		activity = `MAX(c.created_at, COALESCE((
+			SELECT MAX(a.created_at) FROM messages a
+			WHERE a.conversation_id = c.id AND a.kind = 'text' AND a.created_at <= ` + at + `
+		), 0))`
+		snapshot = " AND c.created_at <= " + at + " AND m.joined_at <= " + at
+	}
+
+	cond, args := page.where(activity, "c.id")
+	query := conversationColumns + ", " + activity + conversationFrom + snapshot + cond + page.orderBy(activity, "c.id")
+	rows, err := db.c.QueryContext(ctx, query,
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	conversations = []Conversation{}
+	for rows.Next() {
+		var activity int64
+		c, err := scanConversation(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &activity)...)
+		}))
+		if err != nil {
+			return nil, false, err
+		}
+		c.Activity = fromMillis(activity)
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if more = page.hasMore(len(conversations)); more {
+		conversations = conversations[:len(conversations)-1]
+	}
+	if page.Forward() {
+		for i, j := 0, len(conversations)-1; i < j; i, j = i+1, j-1 {
+			conversations[i], conversations[j] = conversations[j], conversations[i]
+		}
+	}
+	return conversations, more, nil
+}
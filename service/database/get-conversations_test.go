@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetConversationsSnapshot(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice := createUser(t, db, "alice")
+
+	// Six conversations, the most recently active first: c[0] ... c[5]
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := make([]Conversation, 6)
+	for i := range c {
+		setTime(t, start.Add(time.Duration(len(c)-i)*time.Minute))
+		other := createUser(t, db, "user"+string(rune('a'+i)))
+		c[i] = startConversation(t, db, alice.ID, other.ID)
+		sendText(t, db, other.ID, c[i].ID, "hi")
+	}
+
+	// First page, then activity in a conversation of the next pages and a new conversation
+	snapshot := start.Add(time.Hour)
+	setTime(t, snapshot)
+	first, more, err := db.GetConversations(ctx, alice.ID, Page{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !more || len(first) != 2 || first[0].ID != c[0].ID || first[1].ID != c[1].ID {
+		t.Fatalf("first page = %v (more: %v), want c0, c1", first, more)
+	}
+	setTime(t, snapshot.Add(time.Minute))
+	sendText(t, db, alice.ID, c[4].ID, "still there?")
+	late := startConversation(t, db, alice.ID, createUser(t, db, "late").ID)
+
+	// The next pages are as of the snapshot: c4 is neither skipped nor repeated, the new conversation is left out
+	got := first
+	last := first[len(first)-1]
+	for more {
+		var page []Conversation
+		page, more, err = db.GetConversations(ctx, alice.ID, Page{
+			Before: &Cursor{Timestamp: last.Activity, ID: last.ID, Snapshot: snapshot},
+			Limit:  2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, page...)
+		last = page[len(page)-1]
+	}
+	if len(got) != len(c) {
+		t.Fatalf("got %d conversations, want %d", len(got), len(c))
+	}
+	for i := range c {
+		if got[i].ID != c[i].ID {
+			t.Errorf("conversation %d is %s, want %s", i, got[i].ID, c[i].ID)
+		}
+	}
+	// Conversations are returned as they are now, only sorted as of the snapshot
+	if got[4].LastMessage != "still there?" || !got[4].Timestamp.After(snapshot) {
+		t.Errorf("c4 = %+v, want its current state", got[4])
+	}
+
+	// The changes are at the top of the current list
+	current, _, err := db.GetConversations(ctx, alice.ID, Page{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{current[0].ID: true, current[1].ID: true}
+	if len(current) != 2 || !ids[late.ID] || !ids[c[4].ID] {
+		t.Errorf("current first page = %v, want the new conversation and c4", current)
+	}
+}
+
+func TestConversationPreview(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob, eve := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "eve")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	other := startConversation(t, db, bob.ID, eve.ID)
+
+	// The messages of the others are unread, the last one is shown
+	setTime(t, start.Add(time.Minute))
+	sendText(t, db, alice.ID, c.ID, "hello")
+	long := sendText(t, db, alice.ID, c.ID, strings.Repeat("é", previewLength+1))
+	sendText(t, db, bob.ID, other.ID, "hi eve")
+	conversation, err := db.GetConversation(ctx, bob.ID, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat("é", previewLength-1) + "…"; conversation.LastMessage != want || conversation.LastMessageSender != "alice" {
+		t.Errorf("preview = %q by %q, want the long message cut, by alice", conversation.LastMessage, conversation.LastMessageSender)
+	}
+	if conversation.UnreadCount != 2 || !conversation.Timestamp.Equal(start.Add(time.Minute)) {
+		t.Errorf("%d unread at %v, want 2 at %v", conversation.UnreadCount, conversation.Timestamp, start.Add(time.Minute))
+	}
+	if unread, _ := unreadCounts(t, db, alice.ID, c.ID); unread != 0 {
+		t.Errorf("sender: %d unread, want 0", unread)
+	}
+
+	// Deleted messages and attachments without text have placeholders; deleted messages aren't unread anymore
+	if err := db.DeleteMessage(ctx, alice.ID, long.ID); err != nil {
+		t.Fatal(err)
+	}
+	conversation, err = db.GetConversation(ctx, bob.ID, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation.LastMessage != previewDeleted || conversation.UnreadCount != 1 {
+		t.Errorf("after deleting the last message: %q, %d unread, want deleted, 1 unread", conversation.LastMessage,
+			conversation.UnreadCount)
+	}
+	if err := db.RegisterBlob(ctx, "photo", 10); err != nil {
+		t.Fatal(err)
+	}
+	setTime(t, start.Add(2*time.Minute))
+	_, err = db.SaveMessage(ctx, alice.ID, NewMessage{ConversationID: c.ID, Attachment: &Attachment{
+		FileName: "cat.png", MIMEType: "image/png", Size: 10, Checksum: "photo",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The most recently active conversation first
+	conversations, _, err := db.GetConversations(ctx, bob.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 2 || conversations[0].ID != c.ID || conversations[0].LastMessage != previewPhoto ||
+		conversations[1].LastMessage != "hi eve" {
+		t.Errorf("conversations = %+v, want the photo first, then hi eve", conversations)
+	}
+
+	if _, err := db.GetConversation(ctx, eve.ID, c.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("conversation of the others = %v, want ErrForbidden", err)
+	}
+	if _, err := db.GetConversation(ctx, bob.ID, "nonexistent0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown conversation = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+)
+
+// GetGroupInvites returns the invites of a group the user is an admin of, newest first, including those that no longer
+// work.
+func (db *appdbimpl) GetGroupInvites(ctx context.Context, userID string, groupID string) ([]Invite, error) {
+	if err := checkGroupAdmin(ctx, db.c, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.c.QueryContext(ctx, inviteSelect+" WHERE group_id = ? ORDER BY created_at DESC, token", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []Invite{}
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
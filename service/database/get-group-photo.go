@@ -0,0 +1,23 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetGroupPhoto returns the photo of a group the user is a member of. ErrNotFound is returned if the group has no photo.
+func (db *appdbimpl) GetGroupPhoto(ctx context.Context, userID string, groupID string) (Photo, error) {
+	if err := checkGroupMember(ctx, db.c, groupID, userID); err != nil {
+		return Photo{}, err
+	}
+
+	var key, mimeType sql.NullString
+	var updatedAt sql.NullInt64
+	err := db.c.QueryRowContext(ctx, "SELECT photo_key, photo_mime_type, photo_updated_at FROM groups WHERE id = ?", groupID).
+		Scan(&key, &mimeType, &updatedAt)
+	if err != nil {
+		return Photo{}, err
+	}
+	return scanPhoto(fmt.Sprintf("photo of group %s", groupID), key, mimeType, updatedAt)
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetMessageHistory returns the revisions of a message of a conversation the user is a member of, oldest first. The
+// last revision is the current content. The history of deleted messages is not kept (ErrNotFound).
+func (db *appdbimpl) GetMessageHistory(ctx context.Context, userID string, messageID string) ([]Revision, error) {
+	message, err := getVisibleMessage(ctx, db.c, userID, messageID)
+	if err != nil {
+		return nil, err
+	} else if message.Deleted {
+		return nil, fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+	}
+
+	rows, err := db.c.QueryContext(ctx, `
+		SELECT content, created_at FROM message_revisions WHERE message_id = ? ORDER BY revision`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions = []Revision{}
+	for rows.Next() {
+		var r Revision
+		var createdAt int64
+		if err := rows.Scan(&r.Content, &createdAt); err != nil {
+			return nil, err
+		}
+		r.Timestamp = fromMillis(createdAt)
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	current := Revision{Content: message.Content, Timestamp: message.Timestamp}
+	if message.EditedAt != nil {
+		current.Timestamp = *message.EditedAt
+	}
+	return append(revisions, current), nil
+}
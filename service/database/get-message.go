@@ -0,0 +1,10 @@
+package database
+
+import (
+	"context"
+)
+
+// GetMessage returns a message of a conversation the user is a member of.
+func (db *appdbimpl) GetMessage(ctx context.Context, userID string, messageID string) (Message, error) {
+	return getVisibleMessage(ctx, db.c, userID, messageID)
+}
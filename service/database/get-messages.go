@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// messageSelect selects messages with their sender, attachment, the message they reply to, the change recorded by
+// system messages and whether they are pinned, to be scanned with scanMessage.
+const messageSelect = `
+	SELECT m.id, m.conversation_id, m.kind, m.sender_id, u.name, m.content, m.created_at, m.forwarded,
+		m.deleted_at IS NOT NULL, m.edited_at, r.id, r.sender_id, ru.name, r.content, r.deleted_at IS NOT NULL,
+		ra.mime_type, a.id, a.file_name, a.mime_type, a.size, a.width, a.height, a.checksum,
+		s.action, s.target_id, su.name, s.old_value, s.new_value, m.expires_at, p.message_id IS NOT NULL, r.expires_at
+	FROM messages m
+	JOIN users u ON u.id = m.sender_id
+	LEFT JOIN messages r ON r.id = m.reply_to
+	LEFT JOIN users ru ON ru.id = r.sender_id
+	LEFT JOIN attachments ra ON ra.message_id = r.id
+	LEFT JOIN attachments a ON a.message_id = m.id
+	LEFT JOIN system_messages s ON s.message_id = m.id
+	LEFT JOIN users su ON su.id = s.target_id
+	LEFT JOIN pinned_messages p ON p.message_id = m.id`
+
+// notExpired is the condition on the messages m not expired at the time passed as its argument. Expired messages are
+// deleted for good only periodically (see DeleteExpiredMessages): until then, they are left out all the same.
+const notExpired = " AND (m.expires_at IS NULL OR m.expires_at > ?)"
+
+func scanMessage(row scanner) (Message, error) {
+	var m Message
+	var createdAt int64
+	var editedAt, expiresAt, replyExpiresAt sql.NullInt64
+	var replyID, replySenderID, replySender, replyContent, replyMIMEType sql.NullString
+	var replyDeleted sql.NullBool
+	var attachmentID, fileName, mimeType, checksum sql.NullString
+	var size, width, height sql.NullInt64
+	var action, targetID, target, oldValue, newValue sql.NullString
+	err := row.Scan(&m.ID, &m.ConversationID, &m.Kind, &m.SenderID, &m.Sender, &m.Content, &createdAt, &m.Forwarded,
+		&m.Deleted, &editedAt, &replyID, &replySenderID, &replySender, &replyContent, &replyDeleted, &replyMIMEType,
+		&attachmentID, &fileName, &mimeType, &size, &width, &height, &checksum,
+		&action, &targetID, &target, &oldValue, &newValue, &expiresAt, &m.Pinned, &replyExpiresAt)
+	m.Timestamp = fromMillis(createdAt)
+	if editedAt.Valid {
+		t := fromMillis(editedAt.Int64)
+		m.Edited, m.EditedAt = true, &t
+	}
+	if expiresAt.Valid {
+		t := fromMillis(expiresAt.Int64)
+		m.ExpiresAt = &t
+	}
+	m.Status = MessageSent
+	m.Mentions = []Mention{}
+	m.Reactions = []ReactionCount{}
+	// Replies lose the quote of expired messages, as they do once those are deleted
+	if replyID.Valid && (!replyExpiresAt.Valid || replyExpiresAt.Int64 > now()) {
+		m.ReplyTo = &Quote{
+			ID:       replyID.String,
+			SenderID: replySenderID.String,
+			Sender:   replySender.String,
+			Excerpt:  preview(replyContent.String, false, replyMIMEType.String),
+			Deleted:  replyDeleted.Bool,
+		}
+	}
+	if attachmentID.Valid {
+		m.Attachment = &Attachment{
+			ID:       attachmentID.String,
+			FileName: fileName.String,
+			MIMEType: mimeType.String,
+			Size:     size.Int64,
+			Width:    int(width.Int64),
+			Height:   int(height.Int64),
+			Checksum: checksum.String,
+		}
+	}
+	if action.Valid {
+		m.System = &SystemEvent{
+			Action:   action.String,
+			TargetID: targetID.String,
+			Target:   target.String,
+			OldValue: oldValue.String,
+			NewValue: newValue.String,
+		}
+	}
+	return m, err
+}
+
+// GetMessages returns a page of the messages of a conversation the user is a member of, with their reactions. Messages
+// are sorted oldest first regardless of the direction of the page. more reports whether there are other messages past
+// the page, in its direction.
+func (db *appdbimpl) GetMessages(ctx context.Context, userID string, conversationID string, page Page) (messages []Message, more bool, err error) {
+	if err := checkMember(ctx, db.c, conversationID, userID); err != nil {
+		return nil, false, err
+	}
+
+	cond, args := page.where("m.created_at", "m.id")
+	query := messageSelect + " WHERE m.conversation_id = ?" + notExpired + cond + page.orderBy("m.created_at", "m.id")
+	rows, err := db.c.QueryContext(ctx, query, append([]interface{}{conversationID, now()}, args...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	messages = []Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	_ = rows.Close()
+
+	if more = page.hasMore(len(messages)); more {
+		messages = messages[:len(messages)-1]
+	}
+	if !page.Forward() {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	if err := loadReactions(ctx, db.c, userID, messages); err != nil {
+		return nil, false, err
+	}
+	if err := loadStars(ctx, db.c, userID, messages); err != nil {
+		return nil, false, err
+	}
+	if err := loadMentions(ctx, db.c, messages); err != nil {
+		return nil, false, err
+	}
+	if err := loadStatuses(ctx, db.c, conversationID, messages); err != nil {
+		return nil, false, err
+	}
+	return messages, more, nil
+}
+
+// loadReactions fills the reactions of the given messages, counted by emoji in the order they were first used, as seen
+// by userID.
+func loadReactions(ctx context.Context, q querier, userID string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var index = map[string]int{}
+	var args []interface{}
+	for i, m := range messages {
+		index[m.ID] = i
+		args = append(args, m.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT r.message_id, r.emoji, COUNT(*), MAX(r.user_id = ?)
+		FROM reactions r
+		WHERE r.message_id IN (?`+strings.Repeat(", ?", len(args)-1)+`)
+		GROUP BY r.message_id, r.emoji
+		ORDER BY MIN(r.created_at), r.emoji`, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		var c ReactionCount
+		if err := rows.Scan(&messageID, &c.Emoji, &c.Count, &c.Reacted); err != nil {
+			return err
+		}
+		if i, ok := index[messageID]; ok {
+			messages[i].Reactions = append(messages[i].Reactions, c)
+		}
+	}
+	return rows.Err()
+}
+
+// loadStars sets whether the given messages are starred by userID.
+func loadStars(ctx context.Context, q querier, userID string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var index = map[string]int{}
+	var args = []interface{}{userID}
+	for i, m := range messages {
+		index[m.ID] = i
+		args = append(args, m.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT message_id FROM starred_messages
+		WHERE user_id = ? AND message_id IN (?`+strings.Repeat(", ?", len(messages)-1)+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		if err := rows.Scan(&messageID); err != nil {
+			return err
+		}
+		if i, ok := index[messageID]; ok {
+			messages[i].Starred = true
+		}
+	}
+	return rows.Err()
+}
+
+// loadMessages returns the messages with the given identifiers, keyed by identifier, with their mentions, reactions,
+// stars (as seen by userID) and statuses. The messages must be visible to the user; those expired or deleted for good
+// in the meantime are missing from the result.
+func loadMessages(ctx context.Context, q querier, userID string, ids []string) (map[string]Message, error) {
+	var loaded = map[string]Message{}
+	if len(ids) == 0 {
+		return loaded, nil
+	}
+
+	var args []interface{}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := messageSelect + " WHERE m.id IN (?" + strings.Repeat(", ?", len(args)-1) + ")" + notExpired
+	rows, err := q.QueryContext(ctx, query, append(args, now())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Statuses depend on the members of each conversation, so the messages are loaded by conversation
+	var byConversation = map[string][]Message{}
+	var conversations []string
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byConversation[m.ConversationID]; !ok {
+			conversations = append(conversations, m.ConversationID)
+		}
+		byConversation[m.ConversationID] = append(byConversation[m.ConversationID], m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	for _, conversationID := range conversations {
+		messages := byConversation[conversationID]
+		if err := loadReactions(ctx, q, userID, messages); err != nil {
+			return nil, err
+		}
+		if err := loadStars(ctx, q, userID, messages); err != nil {
+			return nil, err
+		}
+		if err := loadMentions(ctx, q, messages); err != nil {
+			return nil, err
+		}
+		if err := loadStatuses(ctx, q, conversationID, messages); err != nil {
+			return nil, err
+		}
+		for _, m := range messages {
+			loaded[m.ID] = m
+		}
+	}
+	return loaded, nil
+}
+
+// loadStatuses sets the delivery status of the given messages of a conversation, from the receipts of its members.
+func loadStatuses(ctx context.Context, q querier, conversationID string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	type memberReceipt struct {
+		userID                           string
+		joinedAt, receivedUpTo, readUpTo int64
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT user_id, joined_at, received_upto, read_upto
+		FROM conversation_members
+		WHERE conversation_id = ?`, conversationID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var members []memberReceipt
+	for rows.Next() {
+		var r memberReceipt
+		if err := rows.Scan(&r.userID, &r.joinedAt, &r.receivedUpTo, &r.readUpTo); err != nil {
+			return err
+		}
+		members = append(members, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// The recipients of a message are the current members, except the sender and those who joined after it was sent.
+	// A message with no recipients left stays sent.
+	for i := range messages {
+		createdAt := messages[i].Timestamp.UnixMilli()
+		status, recipients := MessageRead, 0
+		for _, r := range members {
+			if r.userID == messages[i].SenderID || r.joinedAt > createdAt {
+				continue
+			}
+			recipients++
+			if r.receivedUpTo < createdAt {
+				status = MessageSent
+				break
+			} else if r.readUpTo < createdAt {
+				status = MessageReceived
+			}
+		}
+		if recipients == 0 {
+			status = MessageSent
+		}
+		messages[i].Status = status
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetMessagesPages(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob := createUser(t, db, "alice"), createUser(t, db, "bob")
+	c := startConversation(t, db, alice.ID, bob.ID)
+
+	// Messages sent in the same millisecond are ordered by identifier
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sent []string
+	for i := 0; i < 7; i++ {
+		setTime(t, start.Add(time.Duration(i/3)*time.Second))
+		sent = append(sent, sendText(t, db, alice.ID, c.ID, "hello").ID)
+	}
+	all, _, err := db.GetMessages(ctx, bob.ID, c.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(sent) {
+		t.Fatalf("got %d messages, want %d", len(all), len(sent))
+	}
+	for i := 1; i < len(all); i++ {
+		prev, cur := all[i-1], all[i]
+		if cur.Timestamp.Before(prev.Timestamp) || cur.Timestamp.Equal(prev.Timestamp) && cur.ID <= prev.ID {
+			t.Fatalf("messages not sorted: %v before %v", prev, cur)
+		}
+	}
+	cursor := func(m Message) *Cursor {
+		return &Cursor{Timestamp: m.Timestamp, ID: m.ID}
+	}
+
+	// Walking backward from the most recent message
+	var got []Message
+	page := Page{Limit: 2}
+	for {
+		messages, more, err := db.GetMessages(ctx, bob.ID, c.ID, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(messages, got...)
+		if !more {
+			break
+		}
+		page.Before = cursor(messages[0])
+	}
+	assertSameMessages(t, "backward", got, all)
+
+	// Walking forward from the oldest message
+	got = all[:1]
+	page = Page{After: cursor(all[0]), Limit: 2}
+	for {
+		messages, more, err := db.GetMessages(ctx, bob.ID, c.ID, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, messages...)
+		if !more {
+			break
+		}
+		page.After = cursor(messages[len(messages)-1])
+	}
+	assertSameMessages(t, "forward", got, all)
+
+	// Both cursors select a range
+	messages, more, err := db.GetMessages(ctx, bob.ID, c.ID, Page{After: cursor(all[1]), Before: cursor(all[5])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameMessages(t, "range", messages, all[2:5])
+	if more {
+		t.Error("more messages reported in a closed range")
+	}
+}
+
+func assertSameMessages(t *testing.T, name string, got []Message, want []Message) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d messages, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID {
+			t.Fatalf("%s: message %d is %s, want %s", name, i, got[i].ID, want[i].ID)
+		}
+	}
+}
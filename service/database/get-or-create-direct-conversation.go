@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetOrCreateDirectConversation returns the 1:1 conversation between the user and otherID, creating it if it does not
+// exist yet. created reports whether the conversation has been created by this call.
+func (db *appdbimpl) GetOrCreateDirectConversation(ctx context.Context, userID string, otherID string) (conversation Conversation, created bool, err error) {
+	if userID == otherID {
+		return Conversation{}, false, fmt.Errorf("conversation with yourself: %w", ErrConflict)
+	}
+	userA, userB := userID, otherID
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		var conversationID string
+		err := tx.QueryRowContext(ctx, "SELECT conversation_id FROM direct_conversations WHERE user_a = ? AND user_b = ?",
+			userA, userB).Scan(&conversationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			conversationID, err = newID()
+			if err != nil {
+				return err
+			}
+			if err := insertConversation(ctx, tx, conversationID, false); err != nil {
+				return err
+			}
+			// The members first, as addMember reports unknown users
+			for _, member := range []string{userID, otherID} {
+				if err := addMember(ctx, tx, conversationID, member); err != nil {
+					return err
+				}
+			}
+			_, err = tx.ExecContext(ctx, "INSERT INTO direct_conversations (conversation_id, user_a, user_b) VALUES (?, ?, ?)",
+				conversationID, userA, userB)
+			if err != nil {
+				return err
+			}
+			created = true
+		} else if err != nil {
+			return err
+		}
+
+		conversation, err = scanConversation(tx.QueryRowContext(ctx, conversationSelect+" AND c.id = ?", userID, conversationID))
+		return err
+	})
+	return conversation, created, err
+}
+
+// insertConversation creates an empty conversation.
+func insertConversation(ctx context.Context, tx *sql.Tx, conversationID string, isGroup bool) error {
+	createdAt := now()
+	_, err := tx.ExecContext(ctx, "INSERT INTO conversations (id, is_group, created_at, last_activity_at) VALUES (?, ?, ?, ?)",
+		conversationID, isGroup, createdAt, createdAt)
+	return err
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetOrCreateDirectConversation(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+
+	c, created, err := db.GetOrCreateDirectConversation(ctx, alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || c.IsGroup || c.Name != "bob" {
+		t.Errorf("conversation = %+v, created: %v, want a new conversation named bob", c, created)
+	}
+
+	// The same conversation, from both sides
+	again, created, err := db.GetOrCreateDirectConversation(ctx, bob.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || again.ID != c.ID || again.Name != "alice" {
+		t.Errorf("conversation = %+v, created: %v, want %s named alice", again, created, c.ID)
+	}
+	if other := startConversation(t, db, alice.ID, carol.ID); other.ID == c.ID {
+		t.Error("same conversation with another user")
+	}
+
+	if _, _, err := db.GetOrCreateDirectConversation(ctx, alice.ID, alice.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("conversation with oneself = %v, want ErrConflict", err)
+	}
+	if _, _, err := db.GetOrCreateDirectConversation(ctx, alice.ID, "nonexistent0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("conversation with an unknown user = %v, want ErrNotFound", err)
+	}
+}
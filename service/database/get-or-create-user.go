@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetOrCreateUser returns the user named `name` (case-insensitive), registering it first if needed.
+func (db *appdbimpl) GetOrCreateUser(ctx context.Context, name string) (User, error) {
+	id, err := newID()
+	if err != nil {
+		return User{}, err
+	}
+
+	_, err = db.c.ExecContext(ctx, `INSERT INTO users (id, name, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`, id, name, now())
+	if err != nil {
+		return User{}, fmt.Errorf("creating user: %w", err)
+	}
+
+	var user User
+	err = db.c.QueryRowContext(ctx, "SELECT id, name FROM users WHERE name = ?", name).Scan(&user.ID, &user.Name)
+	return user, err
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// GetPinnedMessages returns the messages pinned in a conversation the user is a member of, the last pinned first.
+func (db *appdbimpl) GetPinnedMessages(ctx context.Context, userID string, conversationID string) ([]Pin, error) {
+	if err := checkMember(ctx, db.c, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.c.QueryContext(ctx, `
+		SELECT message_id, pinned_by, pinned_at FROM pinned_messages
+		WHERE conversation_id = ?
+		ORDER BY pinned_at DESC, message_id DESC`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []Pin
+	var ids []string
+	for rows.Next() {
+		var p Pin
+		var pinnedBy sql.NullString
+		var pinnedAt int64
+		if err := rows.Scan(&p.Message.ID, &pinnedBy, &pinnedAt); err != nil {
+			return nil, err
+		}
+		p.PinnedBy = pinnedBy.String
+		p.PinnedAt = fromMillis(pinnedAt)
+		pins = append(pins, p)
+		ids = append(ids, p.Message.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	messages, err := loadMessages(ctx, db.c, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	loaded := []Pin{}
+	for _, p := range pins {
+		if m, ok := messages[p.Message.ID]; ok {
+			p.Message = m
+			loaded = append(loaded, p)
+		}
+	}
+	return loaded, nil
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+)
+
+// GetScheduledMessages returns the messages scheduled by the user and not sent yet, including the ones that failed, the
+// first to be sent first.
+func (db *appdbimpl) GetScheduledMessages(ctx context.Context, userID string) ([]ScheduledMessage, error) {
+	rows, err := db.c.QueryContext(ctx, scheduledSelect+" WHERE sender_id = ? ORDER BY send_at, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []ScheduledMessage{}
+	for rows.Next() {
+		m, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
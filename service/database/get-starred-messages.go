@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+)
+
+// GetStarredMessages returns a page of the messages starred by the user, in all the conversations they are still a
+// member of, sorted by the time they were starred: most recent first regardless of the direction of the page. more
+// reports whether there are other messages past the page, in its direction.
+func (db *appdbimpl) GetStarredMessages(ctx context.Context, userID string, page Page) (starred []StarredMessage, more bool, err error) {
+	cond, args := page.where("s.starred_at", "s.message_id")
+	rows, err := db.c.QueryContext(ctx, `
+		SELECT s.message_id, s.starred_at FROM starred_messages s
+		JOIN messages m ON m.id = s.message_id
+		JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = s.user_id
+		WHERE s.user_id = ?`+cond+page.orderBy("s.starred_at", "s.message_id"),
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var s StarredMessage
+		var starredAt int64
+		if err := rows.Scan(&s.Message.ID, &starredAt); err != nil {
+			return nil, false, err
+		}
+		s.StarredAt = fromMillis(starredAt)
+		starred = append(starred, s)
+		ids = append(ids, s.Message.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	_ = rows.Close()
+
+	if more = page.hasMore(len(starred)); more {
+		starred, ids = starred[:len(starred)-1], ids[:len(ids)-1]
+	}
+	if page.Forward() {
+		for i, j := 0, len(starred)-1; i < j; i, j = i+1, j-1 {
+			starred[i], starred[j] = starred[j], starred[i]
+		}
+	}
+
+	messages, err := loadMessages(ctx, db.c, userID, ids)
+	if err != nil {
+		return nil, false, err
+	}
+	loaded := []StarredMessage{}
+	for _, s := range starred {
+		if m, ok := messages[s.Message.ID]; ok {
+			s.Message = m
+			loaded = append(loaded, s)
+		}
+	}
+	return loaded, more, nil
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetThumbnail returns the thumbnail of an image blob for the given size. ErrNotFound is returned if there is none:
+// the blob is not an image, or the image is not larger than size.
+func (db *appdbimpl) GetThumbnail(ctx context.Context, key string, size int) (Thumbnail, error) {
+	t := Thumbnail{Size: size}
+	err := db.c.QueryRowContext(ctx, `
+		SELECT thumbnail_key, mime_type, width, height FROM thumbnails WHERE blob_key = ? AND size = ?`, key, size).
+		Scan(&t.Key, &t.MIMEType, &t.Width, &t.Height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, fmt.Errorf("thumbnail %d of %s: %w", size, key, ErrNotFound)
+	}
+	return t, err
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetUserPhoto returns the profile photo of a user. Photos are public: any user can see them. ErrNotFound is returned
+// if the user does not exist or has no photo.
+func (db *appdbimpl) GetUserPhoto(ctx context.Context, userID string) (Photo, error) {
+	var key, mimeType sql.NullString
+	var updatedAt sql.NullInt64
+	err := db.c.QueryRowContext(ctx, "SELECT photo_key, photo_mime_type, photo_updated_at FROM users WHERE id = ?", userID).
+		Scan(&key, &mimeType, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Photo{}, fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	} else if err != nil {
+		return Photo{}, err
+	}
+	return scanPhoto(fmt.Sprintf("photo of user %s", userID), key, mimeType, updatedAt)
+}
+
+// scanPhoto builds a Photo from the nullable photo columns of users and groups. ErrNotFound is returned if there is
+// no photo.
+func scanPhoto(what string, key sql.NullString, mimeType sql.NullString, updatedAt sql.NullInt64) (Photo, error) {
+	if !key.Valid {
+		return Photo{}, fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	return Photo{Key: key.String, MIMEType: mimeType.String, UpdatedAt: fromMillis(updatedAt.Int64)}, nil
+}
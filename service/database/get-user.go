@@ -0,0 +1,18 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetUser returns the user with the given identifier, or ErrNotFound.
+func (db *appdbimpl) GetUser(ctx context.Context, id string) (User, error) {
+	var user User
+	err := db.c.QueryRowContext(ctx, "SELECT id, name FROM users WHERE id = ?", id).Scan(&user.ID, &user.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("user %s: %w", id, ErrNotFound)
+	}
+	return user, err
+}
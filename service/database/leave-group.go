@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// LeaveGroup removes the user from a group, and returns the system message recording it. The group is deleted, with its
+// messages, when its last member leaves (the message is then empty). When its last owner leaves, the oldest admin
+// becomes owner, or the oldest member if there are no admins: newOwnerID is their identifier (empty if the ownership
+// didn't change).
+func (db *appdbimpl) LeaveGroup(ctx context.Context, userID string, groupID string) (newOwnerID string, message Message, err error) {
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupMember(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM conversation_members WHERE conversation_id = ? AND user_id = ?", groupID, userID)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			DELETE FROM conversations
+			WHERE id = ? AND NOT EXISTS(SELECT 1 FROM conversation_members WHERE conversation_id = ?)`, groupID, groupID)
+		if err != nil {
+			return err
+		}
+		if deleted, err := res.RowsAffected(); err != nil || deleted > 0 {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, groupID, userID, SystemEvent{Action: ActionMemberLeft})
+		if err != nil {
+			return err
+		}
+
+		// Transfer the ownership, if the group is still there and has no owner left
+		err = tx.QueryRowContext(ctx, `
+			SELECT gm.user_id
+			FROM group_members gm
+			JOIN conversation_members m ON m.conversation_id = gm.group_id AND m.user_id = gm.user_id
+			WHERE gm.group_id = ?
+				AND NOT EXISTS(SELECT 1 FROM group_members WHERE group_id = gm.group_id AND role = ?)
+			ORDER BY gm.role = ? DESC, m.joined_at, m.user_id
+			LIMIT 1`, groupID, RoleOwner, RoleAdmin).Scan(&newOwnerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?",
+			RoleOwner, groupID, newOwnerID)
+		return err
+	})
+	return newOwnerID, message, err
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLeaveGroup(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	owner, bob, carol := createUser(t, db, "owner"), createUser(t, db, "bob"), createUser(t, db, "carol")
+	dave, eve := createUser(t, db, "dave"), createUser(t, db, "eve")
+	group, err := db.CreateGroup(ctx, owner.ID, "friends", []string{bob.ID, carol.ID, dave.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.PromoteGroupMember(ctx, owner.ID, group.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := db.LeaveGroup(ctx, eve.ID, group.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("LeaveGroup by a non-member = %v, want ErrForbidden", err)
+	}
+
+	// A plain member leaves: nothing else changes
+	newOwnerID, m, err := db.LeaveGroup(ctx, dave.ID, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newOwnerID != "" || m.System == nil || m.System.Action != ActionMemberLeft {
+		t.Errorf("LeaveGroup = %q, %+v, want no new owner and a member.left message", newOwnerID, m.System)
+	}
+	if _, _, err := db.LeaveGroup(ctx, dave.ID, group.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("leaving again = %v, want ErrForbidden", err)
+	}
+
+	// The owner leaves: the admin takes over, although bob joined at the same time
+	newOwnerID, _, err = db.LeaveGroup(ctx, owner.ID, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newOwnerID != carol.ID || memberRole(t, db, group.ID, carol.ID) != RoleOwner {
+		t.Errorf("new owner = %s, want carol", newOwnerID)
+	}
+
+	// Without admins, the oldest member takes over
+	if newOwnerID, _, err := db.LeaveGroup(ctx, carol.ID, group.ID); err != nil || newOwnerID != bob.ID {
+		t.Errorf("new owner = %s, %v, want bob", newOwnerID, err)
+	}
+
+	// The last member leaving deletes the group
+	newOwnerID, m, err = db.LeaveGroup(ctx, bob.ID, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newOwnerID != "" || m.ID != "" {
+		t.Errorf("LeaveGroup of the last member = %q, %+v, want no new owner nor message", newOwnerID, m)
+	}
+	var conversations, messages int
+	if err := db.c.QueryRow("SELECT COUNT(*) FROM conversations WHERE id = ?", group.ID).Scan(&conversations); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.c.QueryRow("SELECT COUNT(*) FROM messages WHERE conversation_id = ?", group.ID).Scan(&messages); err != nil {
+		t.Fatal(err)
+	}
+	if conversations != 0 || messages != 0 {
+		t.Errorf("%d conversations and %d messages left, want the group deleted", conversations, messages)
+	}
+}
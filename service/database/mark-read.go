@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MarkRead records that the user has read the messages of a conversation up to the given message, included. If
+// messageID is empty, all the messages are marked as read. The receipt is returned, with whether it has changed.
+func (db *appdbimpl) MarkRead(ctx context.Context, userID string, conversationID string, messageID string) (Receipt, bool, error) {
+	var receipt Receipt
+	var changed bool
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkMember(ctx, tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		var upTo int64
+		var err error
+		if messageID == "" {
+			err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(created_at), 0) FROM messages WHERE conversation_id = ?",
+				conversationID).Scan(&upTo)
+		} else {
+			err = tx.QueryRowContext(ctx, "SELECT created_at FROM messages WHERE id = ? AND conversation_id = ?",
+				messageID, conversationID).Scan(&upTo)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("message %s in conversation %s: %w", messageID, conversationID, ErrNotFound)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		receipt, changed, err = advanceReceipt(ctx, tx, conversationID, userID, upTo, upTo)
+		return err
+	})
+	return receipt, changed, err
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// messageStatus returns the status of a message, as seen by the user.
+func messageStatus(t *testing.T, db AppDatabase, userID string, messageID string) string {
+	t.Helper()
+	m, err := db.GetMessage(context.Background(), userID, messageID)
+	if err != nil {
+		t.Fatalf("getting message %s: %v", messageID, err)
+	}
+	return m.Status
+}
+
+// unreadCounts returns the unread and mention counters of a conversation for the user.
+func unreadCounts(t *testing.T, db AppDatabase, userID string, conversationID string) (int, int) {
+	t.Helper()
+	c, err := db.GetConversation(context.Background(), userID, conversationID)
+	if err != nil {
+		t.Fatalf("getting conversation %s: %v", conversationID, err)
+	}
+	return c.UnreadCount, c.MentionCount
+}
+
+func TestReceipts(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	carol := createUser(t, db, "carol")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID, carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setTime(t, start.Add(time.Minute))
+	m1 := sendText(t, db, alice.ID, group.ID, "hello")
+	setTime(t, start.Add(2*time.Minute))
+	m2 := sendText(t, db, alice.ID, group.ID, "are you there, @bob?")
+	if s := messageStatus(t, db, alice.ID, m2.ID); s != MessageSent {
+		t.Errorf("status = %s, want %s", s, MessageSent)
+	}
+	if unread, mentions := unreadCounts(t, db, bob.ID, group.ID); unread != 2 || mentions != 1 {
+		t.Errorf("bob: unread %d, mentions %d, want 2, 1", unread, mentions)
+	}
+	if unread, _ := unreadCounts(t, db, alice.ID, group.ID); unread != 0 {
+		t.Errorf("alice: unread %d, want 0", unread)
+	}
+
+	// Received by all the recipients
+	setTime(t, start.Add(3*time.Minute))
+	if _, changed, err := db.MarkReceived(ctx, bob.ID, group.ID, m2.Timestamp); err != nil || !changed {
+		t.Fatalf("MarkReceived = %v, %v, want changed", changed, err)
+	}
+	if s := messageStatus(t, db, alice.ID, m2.ID); s != MessageSent {
+		t.Errorf("status after bob received = %s, want %s", s, MessageSent)
+	}
+	if _, _, err := db.MarkReceived(ctx, carol.ID, group.ID, m2.Timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if s := messageStatus(t, db, alice.ID, m2.ID); s != MessageReceived {
+		t.Errorf("status after all received = %s, want %s", s, MessageReceived)
+	}
+
+	// Watermarks never move backward
+	receipt, changed, err := db.MarkReceived(ctx, bob.ID, group.ID, m1.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || !receipt.ReceivedUpTo.Equal(m2.Timestamp) {
+		t.Errorf("MarkReceived backward = %v (changed: %v), want received up to %v", receipt, changed, m2.Timestamp)
+	}
+
+	// Read up to a message: the counters follow
+	if _, changed, err := db.MarkRead(ctx, bob.ID, group.ID, m1.ID); err != nil || !changed {
+		t.Fatalf("MarkRead = %v, %v, want changed", changed, err)
+	}
+	if unread, mentions := unreadCounts(t, db, bob.ID, group.ID); unread != 1 || mentions != 1 {
+		t.Errorf("bob: unread %d, mentions %d, want 1, 1", unread, mentions)
+	}
+	if _, _, err := db.MarkRead(ctx, carol.ID, group.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := messageStatus(t, db, alice.ID, m1.ID); s != MessageRead {
+		t.Errorf("status of m1 = %s, want %s", s, MessageRead)
+	}
+	if s := messageStatus(t, db, alice.ID, m2.ID); s != MessageReceived {
+		t.Errorf("status of m2 = %s, want %s", s, MessageReceived)
+	}
+	if _, changed, err := db.MarkRead(ctx, bob.ID, group.ID, m1.ID); err != nil || changed {
+		t.Errorf("MarkRead again = %v, %v, want unchanged", changed, err)
+	}
+
+	// Sending doesn't read: m2 is still unread by bob
+	setTime(t, start.Add(4*time.Minute))
+	reply := sendText(t, db, bob.ID, group.ID, "yes")
+	if s := messageStatus(t, db, alice.ID, m2.ID); s != MessageReceived {
+		t.Errorf("status of m2 after bob replied = %s, want %s", s, MessageReceived)
+	}
+	if unread, mentions := unreadCounts(t, db, bob.ID, group.ID); unread != 1 || mentions != 1 {
+		t.Errorf("bob after replying: unread %d, mentions %d, want 1, 1", unread, mentions)
+	}
+	if unread, _ := unreadCounts(t, db, alice.ID, group.ID); unread != 1 {
+		t.Errorf("alice: unread %d, want 1", unread)
+	}
+
+	// Deleted messages aren't unread anymore
+	if err := db.DeleteMessage(ctx, bob.ID, reply.ID); err != nil {
+		t.Fatal(err)
+	}
+	if unread, _ := unreadCounts(t, db, alice.ID, group.ID); unread != 0 {
+		t.Errorf("alice after deletion: unread %d, want 0", unread)
+	}
+	if _, _, err := db.MarkRead(ctx, bob.ID, group.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if unread, mentions := unreadCounts(t, db, bob.ID, group.ID); unread != 0 || mentions != 0 {
+		t.Errorf("bob after reading all: unread %d, mentions %d, want 0, 0", unread, mentions)
+	}
+}
+
+func TestMarkReadNotMember(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	eve := createUser(t, db, "eve")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	m := sendText(t, db, alice.ID, c.ID, "hi")
+
+	if _, _, err := db.MarkRead(ctx, eve.ID, c.ID, m.ID); err == nil {
+		t.Error("MarkRead by a non-member succeeded")
+	}
+	if _, _, err := db.MarkRead(ctx, bob.ID, c.ID, "nonexistent"); err == nil {
+		t.Error("MarkRead of an unknown message succeeded")
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MarkReceived records that the user has received the messages of a conversation sent up to the given time. The
+// receipt is returned, with whether it has changed (watermarks never move backward).
+func (db *appdbimpl) MarkReceived(ctx context.Context, userID string, conversationID string, upTo time.Time) (Receipt, bool, error) {
+	var receipt Receipt
+	var changed bool
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkMember(ctx, tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		var err error
+		receipt, changed, err = advanceReceipt(ctx, tx, conversationID, userID, upTo.UnixMilli(), 0)
+		return err
+	})
+	return receipt, changed, err
+}
+
+// advanceReceipt moves the watermarks of a member forward to the given times (Unix milliseconds), if they are behind.
+// When the read watermark moves, the unread and mention counters are recomputed from the messages still unread, which
+// are usually few (and found through the messages_by_conversation index).
+func advanceReceipt(ctx context.Context, tx *sql.Tx, conversationID string, userID string, receivedUpTo int64, readUpTo int64) (Receipt, bool, error) {
+	if readUpTo > receivedUpTo {
+		// Read messages have been received too
+		receivedUpTo = readUpTo
+	}
+
+	var oldReceived, oldRead int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT received_upto, read_upto FROM conversation_members WHERE conversation_id = ? AND user_id = ?`,
+		conversationID, userID).Scan(&oldReceived, &oldRead)
+	if err != nil {
+		return Receipt{}, false, err
+	}
+
+	changed := receivedUpTo > oldReceived || readUpTo > oldRead
+	if receivedUpTo < oldReceived {
+		receivedUpTo = oldReceived
+	}
+	if readUpTo < oldRead {
+		readUpTo = oldRead
+	}
+	if changed {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE conversation_members SET received_upto = ?, read_upto = ? WHERE conversation_id = ? AND user_id = ?`,
+			receivedUpTo, readUpTo, conversationID, userID)
+		if err != nil {
+			return Receipt{}, false, err
+		}
+	}
+	if readUpTo > oldRead {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE conversation_members SET unread_count = (
+				SELECT COUNT(*) FROM messages m
+				WHERE m.conversation_id = conversation_members.conversation_id
+					AND m.created_at > conversation_members.read_upto
+					AND m.created_at >= conversation_members.joined_at
+					AND m.sender_id != conversation_members.user_id
+					AND m.kind = 'text'
+					AND m.deleted_at IS NULL
+			), mention_count = (
+				SELECT COUNT(*) FROM messages m
+				WHERE m.conversation_id = conversation_members.conversation_id
+					AND m.created_at > conversation_members.read_upto
+					AND m.created_at >= conversation_members.joined_at
+					AND m.deleted_at IS NULL
+					AND EXISTS(
+						SELECT 1 FROM message_mentions mm WHERE mm.message_id = m.id AND mm.user_id = conversation_members.user_id
+					)
+			)
+			WHERE conversation_id = ? AND user_id = ?`, conversationID, userID)
+		if err != nil {
+			return Receipt{}, false, err
+		}
+	}
+
+	return Receipt{
+		ConversationID: conversationID,
+		UserID:         userID,
+		ReceivedUpTo:   fromMillis(receivedUpTo),
+		ReadUpTo:       fromMillis(readUpTo),
+	}, changed, nil
+}
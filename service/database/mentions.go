@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNameLength is the maximum length of user names, which are made of ASCII letters, digits, '_' and '-'.
+const maxNameLength = 16
+
+// mentionedName is an `@name` found in the content of a message, at start (in UTF-16 code units).
+type mentionedName struct {
+	name   string
+	start  int
+	length int
+}
+
+// isNameByte reports whether c can be part of a user name.
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// findMentions returns the `@name` in content. The name is the longest sequence of name characters after the '@', which
+// must not follow a name character itself (as in e-mail addresses).
+func findMentions(content string) []mentionedName {
+	var mentions []mentionedName
+	offset := 0 // in UTF-16 code units
+	for i := 0; i < len(content); {
+		if content[i] == '@' && (i == 0 || !isNameByte(content[i-1])) {
+			end := i + 1
+			for end < len(content) && isNameByte(content[end]) {
+				end++
+			}
+			if n := end - i - 1; n >= 1 && n <= maxNameLength {
+				mentions = append(mentions, mentionedName{name: content[i+1 : end], start: offset, length: n + 1})
+			}
+			if end > i+1 {
+				// Names are ASCII: one code unit per byte
+				offset += end - i
+				i = end
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r >= 0x10000 {
+			offset += 2 // surrogate pair
+		} else {
+			offset++
+		}
+		i += size
+	}
+	return mentions
+}
+
+// saveMentions records the mentions of the members of a group in the content of a new message (or a new revision of
+// it), resolving the names against the current members. The sender can't mention themselves, and mentions in 1:1
+// conversations are not recorded.
+func saveMentions(ctx context.Context, tx *sql.Tx, conversationID string, senderID string, messageID string, content string) error {
+	found := findMentions(content)
+	if len(found) == 0 {
+		return nil
+	}
+	var isGroup bool
+	if err := tx.QueryRowContext(ctx, "SELECT is_group FROM conversations WHERE id = ?", conversationID).Scan(&isGroup); err != nil {
+		return err
+	} else if !isGroup {
+		return nil
+	}
+
+	// Names are unique regardless of case
+	rows, err := tx.QueryContext(ctx, `
+		SELECT u.id, u.name FROM conversation_members cm JOIN users u ON u.id = cm.user_id
+		WHERE cm.conversation_id = ? AND cm.user_id != ?`, conversationID, senderID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	members := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		members[strings.ToLower(name)] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_ = rows.Close()
+
+	for _, m := range found {
+		userID, ok := members[strings.ToLower(m.name)]
+		if !ok {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO message_mentions (message_id, user_id, start, length) VALUES (?, ?, ?, ?)",
+			messageID, userID, m.start, m.length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countMentions adds delta to the mention counters of the members mentioned by a message sent at createdAt (Unix
+// milliseconds), if they haven't read it yet. A message mentioning a member more than once counts once.
+func countMentions(ctx context.Context, tx *sql.Tx, conversationID string, messageID string, createdAt int64, delta int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE conversation_members SET mention_count = MAX(mention_count + ?, 0)
+		WHERE conversation_id = ? AND user_id IN (SELECT user_id FROM message_mentions WHERE message_id = ?)
+			AND joined_at <= ? AND read_upto < ?`, delta, conversationID, messageID, createdAt, createdAt)
+	return err
+}
+
+// loadMentions fills the mentions of the given messages, in the order they appear in the content, with the current
+// names of the users mentioned.
+func loadMentions(ctx context.Context, q querier, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var index = map[string]int{}
+	var args []interface{}
+	for i, m := range messages {
+		index[m.ID] = i
+		args = append(args, m.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT mm.message_id, mm.user_id, u.name, mm.start, mm.length
+		FROM message_mentions mm
+		JOIN users u ON u.id = mm.user_id
+		WHERE mm.message_id IN (?`+strings.Repeat(", ?", len(args)-1)+`)
+		ORDER BY mm.message_id, mm.start`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		var m Mention
+		if err := rows.Scan(&messageID, &m.UserID, &m.Name, &m.Offset, &m.Length); err != nil {
+			return err
+		}
+		if i, ok := index[messageID]; ok {
+			messages[i].Mentions = append(messages[i].Mentions, m)
+		}
+	}
+	return rows.Err()
+}
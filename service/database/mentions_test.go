@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Slice returns the part of s between the given offsets in UTF-16 code units, as clients slice the content.
+func utf16Slice(s string, start int, length int) string {
+	units := utf16.Encode([]rune(s))
+	if start < 0 || start+length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[start : start+length]))
+}
+
+func TestFindMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []mentionedName
+	}{
+		{"@bob hi", []mentionedName{{"bob", 0, 4}}},
+		{"hi @bob!", []mentionedName{{"bob", 3, 4}}},
+		{"é @bob", []mentionedName{{"bob", 2, 4}}},  // one code unit, two bytes
+		{"日本 @bob", []mentionedName{{"bob", 3, 4}}}, // one code unit, three bytes
+		{"😀 @bob", []mentionedName{{"bob", 3, 4}}},  // two code units, four bytes
+		{"😀😀@bob", []mentionedName{{"bob", 4, 4}}},  // right after a non-name character
+		{"@alice, @bob_-1.", []mentionedName{{"alice", 0, 6}, {"bob_-1", 8, 7}}},
+		{"@@bob", []mentionedName{{"bob", 1, 4}}},
+		{"@abcdefghijklmnop", []mentionedName{{"abcdefghijklmnop", 0, 17}}},
+		{"@abcdefghijklmnopq", nil}, // longer than any name
+		{"bob@example.com", nil},
+		{"@", nil},
+		{"@ bob", nil},
+		{"@😀bob", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := findMentions(tt.content)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findMentions(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+		for _, m := range got {
+			if s := utf16Slice(tt.content, m.start, m.length); s != "@"+m.name {
+				t.Errorf("findMentions(%q): %q at %d, want @%s", tt.content, s, m.start, m.name)
+			}
+		}
+	}
+}
+
+func TestSaveMentions(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "Bob")
+	carol := createUser(t, db, "carol")
+	eve := createUser(t, db, "eve")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID, carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mentions resolve against the members other than the sender, regardless of case
+	content := "😀 @bob, @BOB and @eve, @alice @carol"
+	m := sendText(t, db, alice.ID, group.ID, content)
+	want := []Mention{
+		{UserID: bob.ID, Name: "Bob", Offset: 3, Length: 4},
+		{UserID: bob.ID, Name: "Bob", Offset: 9, Length: 4},
+		{UserID: carol.ID, Name: "carol", Offset: 31, Length: 6},
+	}
+	if !reflect.DeepEqual(m.Mentions, want) {
+		t.Errorf("mentions = %+v, want %+v", m.Mentions, want)
+	}
+	for _, mention := range m.Mentions {
+		if s := utf16Slice(content, mention.Offset, mention.Length); !strings.EqualFold(s, "@"+mention.Name) {
+			t.Errorf("mention of %s at %d is %q", mention.Name, mention.Offset, s)
+		}
+	}
+
+	// A message counts once for each member mentioned
+	if _, mentions := unreadCounts(t, db, bob.ID, group.ID); mentions != 1 {
+		t.Errorf("bob: %d mentions, want 1", mentions)
+	}
+	if _, mentions := unreadCounts(t, db, carol.ID, group.ID); mentions != 1 {
+		t.Errorf("carol: %d mentions, want 1", mentions)
+	}
+	if _, mentions := unreadCounts(t, db, alice.ID, group.ID); mentions != 0 {
+		t.Errorf("alice: %d mentions of herself, want 0", mentions)
+	}
+
+	// Editing the message recounts them
+	edited, err := db.EditMessage(ctx, alice.ID, m.ID, "😀 @bob", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited.Mentions) != 1 || edited.Mentions[0].UserID != bob.ID || edited.Mentions[0].Offset != 3 {
+		t.Errorf("mentions after editing = %+v, want bob at 3", edited.Mentions)
+	}
+	if _, mentions := unreadCounts(t, db, carol.ID, group.ID); mentions != 0 {
+		t.Errorf("carol after the edit: %d mentions, want 0", mentions)
+	}
+	if _, mentions := unreadCounts(t, db, bob.ID, group.ID); mentions != 1 {
+		t.Errorf("bob after the edit: %d mentions, want 1", mentions)
+	}
+
+	// 1:1 conversations have no mentions
+	c := startConversation(t, db, alice.ID, eve.ID)
+	if m := sendText(t, db, alice.ID, c.ID, "@eve"); len(m.Mentions) != 0 {
+		t.Errorf("mentions in a 1:1 conversation = %+v", m.Mentions)
+	}
+}
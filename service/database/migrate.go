@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles contains the schema migrations. Each file is named `<version>_<description>.sql`, where version is a
+// positive number. Versions must be contiguous starting from 1: a new migration is added by creating the next file,
+// existing files must never be modified once released.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// fts5Migrations are the migrations creating the full-text index of the messages, which needs the FTS5 extension of
+// SQLite. Without it, they are recorded as applied without running them: the index is then created by setupSearch (see
+// search-index.go) the first time the database is opened with FTS5.
+var fts5Migrations = map[int]bool{12: true}
+
+// ErrSchemaTooNew is returned by New when the database has been migrated by a newer version of this program.
+var ErrSchemaTooNew = errors.New("database schema is newer than the supported one")
+
+type migration struct {
+	version int
+	name    string
+	script  string
+}
+
+// loadMigrations reads the embedded migrations, sorted by version.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading embedded migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix := strings.SplitN(name, "_", 2)[0]
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+
+		script, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %q: %w", name, err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, script: string(script)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %q: expected version %d", m.name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// migrate brings the schema to the latest version embedded in the executable. The current version is stored in
+// `PRAGMA user_version`; each pending migration is applied in its own transaction together with the version bump, so a
+// failure leaves the database at the last successfully applied version.
+//
+// Foreign keys are disabled while migrating (SQLite does not allow changing this setting inside a transaction, and
+// table rebuilds would otherwise cascade deletions); their consistency is checked before each commit instead. fts5
+// tells whether SQLite has the FTS5 extension, needed by fts5Migrations.
+func migrate(db *sql.DB, fts5 bool) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var current int
+	if err := conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("%w: database is at version %d, this executable supports up to version %d",
+			ErrSchemaTooNew, current, len(migrations))
+	}
+	if current == len(migrations) {
+		return nil
+	}
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("reading foreign keys setting: %w", err)
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("disabling foreign keys: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		}()
+	}
+
+	for _, m := range migrations[current:] {
+		if fts5Migrations[m.version] && !fts5 {
+			m.script = ""
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("applying migration %q: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a single migration script and records its version, atomically.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.script); err != nil {
+		return err
+	}
+
+	// PRAGMA statements do not support placeholders; version is an integer parsed by loadMigrations.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return fmt.Errorf("updating schema version: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return fmt.Errorf("checking foreign keys: %w", err)
+	}
+	violation := rows.Next()
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return fmt.Errorf("checking foreign keys: %w", err)
+	} else if violation {
+		return errors.New("foreign key constraints violated")
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func schemaVersion(t *testing.T, conn *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func tableExists(t *testing.T, conn *sql.DB, name string) bool {
+	t.Helper()
+	var exists bool
+	err := conn.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", name).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+// applyMigrations applies the migrations up to version, as migrate would.
+func applyMigrations(t *testing.T, conn *sql.DB, version int) {
+	t.Helper()
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := conn.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, m := range migrations[schemaVersion(t, conn):version] {
+		if err := applyMigration(context.Background(), c, m); err != nil {
+			t.Fatalf("applying %s: %v", m.name, err)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.name, m.version, i+1)
+		}
+		if m.script == "" {
+			t.Errorf("migration %s is empty", m.name)
+		}
+	}
+}
+
+func TestMigrateFromBaseline(t *testing.T) {
+	// The schema created by the versions before migrations
+	conn := openTestConn(t)
+	if _, err := conn.Exec("CREATE TABLE example_table (id INTEGER NOT NULL PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(conn); err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	migrations, _ := loadMigrations()
+	if got := schemaVersion(t, conn); got != len(migrations) {
+		t.Errorf("schema version = %d, want %d", got, len(migrations))
+	}
+	if tableExists(t, conn, "example_table") {
+		t.Error("example_table still exists")
+	}
+	for _, table := range []string{"users", "conversations", "conversation_members", "messages", "reactions"} {
+		if !tableExists(t, conn, table) {
+			t.Errorf("table %s missing", table)
+		}
+	}
+
+	// Opening it again does nothing
+	if _, err := New(conn); err != nil {
+		t.Fatalf("New on an up-to-date database: %v", err)
+	}
+}
+
+func TestMigrateKeepsData(t *testing.T) {
+	conn := openTestConn(t)
+	applyMigrations(t, conn, 1)
+	_, err := conn.Exec(`
+		INSERT INTO users (id, name, photo, created_at) VALUES ('u1', 'alice', x'89504e47', 1), ('u2', 'bob', NULL, 1);
+		INSERT INTO conversations (id, is_group, created_at) VALUES ('c1', 1, 2);
+		INSERT INTO groups (id, name, created_by) VALUES ('c1', 'friends', 'u1');
+		INSERT INTO conversation_members (conversation_id, user_id, is_admin, joined_at) VALUES ('c1', 'u1', 1, 2), ('c1', 'u2', 0, 2);
+		INSERT INTO messages (id, conversation_id, sender_id, content, created_at) VALUES ('m1', 'c1', 'u1', 'hello', 3);
+		INSERT INTO reactions (message_id, user_id, type, created_at) VALUES ('m1', 'u2', 'laugh', 4);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := New(conn)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	messages, _, err := db.GetMessages(context.Background(), "u2", "c1", Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].Content != "hello" || messages[0].Sender != "alice" {
+		t.Fatalf("messages = %+v, want the one sent before migrating", messages)
+	}
+	if r := messages[0].Reactions; len(r) != 1 || r[0].Emoji != "😂" || r[0].Count != 1 {
+		t.Errorf("reactions = %+v, want one 😂", r)
+	}
+	if u, err := db.GetUser(context.Background(), "u1"); err != nil || u.Name != "alice" {
+		t.Errorf("GetUser = %+v, %v", u, err)
+	}
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	conn := openTestConn(t)
+	migrations, _ := loadMigrations()
+	if _, err := conn.Exec("PRAGMA user_version = " + strconv.Itoa(len(migrations)+1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(conn); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("New = %v, want ErrSchemaTooNew", err)
+	}
+	if tableExists(t, conn, "users") {
+		t.Error("database modified")
+	}
+}
+
+func TestApplyMigrationIsAtomic(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"failing statement", "CREATE TABLE a (id TEXT PRIMARY KEY); INSERT INTO missing VALUES (1);", "no such table"},
+		{"foreign key violation", `
+			CREATE TABLE a (id TEXT PRIMARY KEY);
+			CREATE TABLE b (a_id TEXT REFERENCES a (id));
+			INSERT INTO b VALUES ('x');`, "foreign key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := openTestConn(t)
+			c, err := conn.Conn(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			// As in migrate, foreign keys are only checked before committing
+			if _, err := c.ExecContext(context.Background(), "PRAGMA foreign_keys = OFF"); err != nil {
+				t.Fatal(err)
+			}
+
+			err = applyMigration(context.Background(), c, migration{version: 1, name: "0001_test.sql", script: tt.script})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("applyMigration = %v, want an error about %s", err, tt.want)
+			}
+			if got := schemaVersion(t, conn); got != 0 {
+				t.Errorf("schema version = %d, want 0", got)
+			}
+			if tableExists(t, conn, "a") {
+				t.Error("migration partially applied")
+			}
+		})
+	}
+}
+
+func TestMigrateWithoutFTS5(t *testing.T) {
+	conn := openTestConn(t)
+	if err := migrate(conn, false); err != nil {
+		t.Fatalf("migrate without FTS5: %v", err)
+	}
+	migrations, _ := loadMigrations()
+	if got := schemaVersion(t, conn); got != len(migrations) {
+		t.Errorf("schema version = %d, want %d", got, len(migrations))
+	}
+	if tableExists(t, conn, "message_search") {
+		t.Error("full-text index created without FTS5")
+	}
+
+	// The index is created once the database is opened with FTS5
+	fts5, err := hasFTS5(conn)
+	if err != nil {
+		t.Fatal(err)
+	} else if !fts5 {
+		t.Skip("SQLite built without FTS5")
+	}
+	db, err := New(conn)
+	if err != nil {
+		t.Fatalf("New with FTS5: %v", err)
+	}
+	if !tableExists(t, conn, "message_search") {
+		t.Error("full-text index missing")
+	}
+	alice, bob := createUser(t, db, "alice"), createUser(t, db, "bob")
+	sendText(t, db, alice.ID, startConversation(t, db, alice.ID, bob.ID).ID, "hello")
+	if results, _, err := db.SearchMessages(context.Background(), bob.ID, Search{Text: "hello", Limit: 10}); err != nil || len(results) != 1 {
+		t.Errorf("search = %+v, %v, want the message", results, err)
+	}
+}
@@ -0,0 +1,58 @@
+-- Initial WASAText schema.
+--
+-- Identifiers are random strings generated by the application. Timestamps are stored as Unix time in milliseconds
+-- (UTC), so that they sort correctly and can be compared without parsing.
+
+CREATE TABLE users (
+	id         TEXT    NOT NULL PRIMARY KEY,
+	name       TEXT    NOT NULL UNIQUE COLLATE NOCASE,
+	photo      BLOB,
+	created_at INTEGER NOT NULL
+);
+
+-- Every chat is a conversation: both 1:1 chats and groups. Group-specific data lives in the groups table.
+CREATE TABLE conversations (
+	id         TEXT    NOT NULL PRIMARY KEY,
+	is_group   INTEGER NOT NULL DEFAULT 0 CHECK (is_group IN (0, 1)),
+	created_at INTEGER NOT NULL
+);
+
+CREATE TABLE groups (
+	id         TEXT NOT NULL PRIMARY KEY REFERENCES conversations (id) ON DELETE CASCADE,
+	name       TEXT NOT NULL,
+	photo      BLOB,
+	created_by TEXT REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE conversation_members (
+	conversation_id TEXT    NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+	user_id         TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	is_admin        INTEGER NOT NULL DEFAULT 0 CHECK (is_admin IN (0, 1)),
+	joined_at       INTEGER NOT NULL,
+	PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_members_by_user ON conversation_members (user_id);
+
+CREATE TABLE messages (
+	id              TEXT    NOT NULL PRIMARY KEY,
+	conversation_id TEXT    NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+	sender_id       TEXT    NOT NULL REFERENCES users (id),
+	content         TEXT    NOT NULL,
+	forwarded       INTEGER NOT NULL DEFAULT 0 CHECK (forwarded IN (0, 1)),
+	created_at      INTEGER NOT NULL,
+	deleted_at      INTEGER
+);
+
+CREATE INDEX messages_by_conversation ON messages (conversation_id, created_at);
+
+CREATE TABLE reactions (
+	message_id TEXT    NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	user_id    TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	type       TEXT    NOT NULL,
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (message_id, user_id)
+);
+
+-- Leftover of the project template.
+DROP TABLE IF EXISTS example_table;
@@ -0,0 +1,10 @@
+-- Each pair of users has at most one 1:1 conversation. The pair is stored in a canonical order (user_a < user_b) so
+-- that the UNIQUE constraint does not depend on who started the conversation.
+
+CREATE TABLE direct_conversations (
+	conversation_id TEXT NOT NULL PRIMARY KEY REFERENCES conversations (id) ON DELETE CASCADE,
+	user_a          TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	user_b          TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	CHECK (user_a < user_b),
+	UNIQUE (user_a, user_b)
+);
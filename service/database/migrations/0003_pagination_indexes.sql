@@ -0,0 +1,6 @@
+-- Indexes matching the (timestamp, id) cursors used to paginate conversation histories and conversation lists.
+
+DROP INDEX messages_by_conversation;
+CREATE INDEX messages_by_conversation ON messages (conversation_id, created_at, id);
+
+CREATE INDEX conversations_by_creation ON conversations (created_at, id);
@@ -0,0 +1,5 @@
+-- Delivery and read receipts. Each member has two watermarks, holding the creation time of the latest message they have
+-- received and read: every older message is received (or read) as well. 0 means nothing yet.
+
+ALTER TABLE conversation_members ADD COLUMN received_upto INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE conversation_members ADD COLUMN read_upto INTEGER NOT NULL DEFAULT 0;
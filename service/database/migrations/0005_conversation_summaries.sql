@@ -0,0 +1,29 @@
+-- Summary of each conversation for the conversation list, maintained when messages are sent, deleted and read instead
+-- of being computed from the messages on every request.
+
+-- Latest message, and time of the latest activity (the latest message, or the creation of the conversation).
+ALTER TABLE conversations ADD COLUMN last_message_id TEXT REFERENCES messages (id) ON DELETE SET NULL;
+ALTER TABLE conversations ADD COLUMN last_activity_at INTEGER NOT NULL DEFAULT 0;
+
+UPDATE conversations SET last_message_id = (
+	SELECT m.id FROM messages m WHERE m.conversation_id = conversations.id ORDER BY m.created_at DESC, m.id DESC LIMIT 1
+);
+UPDATE conversations SET last_activity_at = COALESCE(
+	(SELECT m.created_at FROM messages m WHERE m.id = conversations.last_message_id),
+	created_at
+);
+
+DROP INDEX conversations_by_creation;
+CREATE INDEX conversations_by_activity ON conversations (last_activity_at, id);
+
+-- Number of messages from other members, sent after the member joined, not deleted and not read yet.
+ALTER TABLE conversation_members ADD COLUMN unread_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE conversation_members SET unread_count = (
+	SELECT COUNT(*) FROM messages m
+	WHERE m.conversation_id = conversation_members.conversation_id
+		AND m.sender_id != conversation_members.user_id
+		AND m.created_at >= conversation_members.joined_at
+		AND m.created_at > conversation_members.read_upto
+		AND m.deleted_at IS NULL
+);
@@ -0,0 +1,3 @@
+-- Replies: a message may quote an earlier message of the same conversation.
+
+ALTER TABLE messages ADD COLUMN reply_to TEXT REFERENCES messages (id) ON DELETE SET NULL;
@@ -0,0 +1,12 @@
+-- Message editing. Each edit moves the previous content of the message to message_revisions, numbered from 1 in the
+-- order of the edits; created_at is the time that content was set (the sending time for the first revision).
+
+ALTER TABLE messages ADD COLUMN edited_at INTEGER;
+
+CREATE TABLE message_revisions (
+	message_id TEXT    NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	revision   INTEGER NOT NULL,
+	content    TEXT    NOT NULL,
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (message_id, revision)
+);
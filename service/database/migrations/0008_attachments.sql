@@ -0,0 +1,15 @@
+-- Files attached to messages (at most one per message). The metadata is extracted when the file is uploaded: the MIME
+-- type is sniffed from the content, the dimensions are set for decodable images only, the checksum is the hex SHA-256.
+
+CREATE TABLE attachments (
+	id         TEXT    NOT NULL PRIMARY KEY,
+	message_id TEXT    NOT NULL UNIQUE REFERENCES messages (id) ON DELETE CASCADE,
+	file_name  TEXT    NOT NULL,
+	mime_type  TEXT    NOT NULL,
+	size       INTEGER NOT NULL,
+	width      INTEGER,
+	height     INTEGER,
+	checksum   TEXT    NOT NULL,
+	data       BLOB    NOT NULL,
+	created_at INTEGER NOT NULL
+);
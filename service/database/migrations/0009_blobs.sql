@@ -0,0 +1,97 @@
+-- Photos and attachments are moved to a content-addressed blob store, outside the database (see service/blobstore).
+-- The blobs table records the stored blobs, keyed by their hex SHA-256, and counts their references: attachments,
+-- user photos and group photos. The counts are kept by the triggers below. A blob with no references is released
+-- (released_at is set), and it's removed from the store by the garbage collector after a grace period; blobs are
+-- registered with no references right before being uploaded, so that the period also covers uploads in progress.
+
+CREATE TABLE blobs (
+	key         TEXT    NOT NULL PRIMARY KEY,
+	size        INTEGER NOT NULL,
+	refcount    INTEGER NOT NULL DEFAULT 0 CHECK (refcount >= 0),
+	released_at INTEGER
+);
+
+CREATE INDEX blobs_released ON blobs (released_at) WHERE refcount = 0;
+
+-- Attachments are identified by their checksum in the store. The content of existing attachments is kept in
+-- legacy_blobs until the application moves it to the store (once, at startup).
+INSERT INTO blobs (key, size, refcount)
+SELECT checksum, MAX(size), COUNT(*) FROM attachments GROUP BY checksum;
+
+CREATE TABLE legacy_blobs (
+	key  TEXT NOT NULL PRIMARY KEY,
+	data BLOB NOT NULL
+);
+
+INSERT INTO legacy_blobs (key, data)
+SELECT checksum, data FROM attachments GROUP BY checksum;
+
+CREATE TABLE attachments_new (
+	id         TEXT    NOT NULL PRIMARY KEY,
+	message_id TEXT    NOT NULL UNIQUE REFERENCES messages (id) ON DELETE CASCADE,
+	file_name  TEXT    NOT NULL,
+	mime_type  TEXT    NOT NULL,
+	size       INTEGER NOT NULL,
+	width      INTEGER,
+	height     INTEGER,
+	checksum   TEXT    NOT NULL REFERENCES blobs (key),
+	created_at INTEGER NOT NULL
+);
+
+INSERT INTO attachments_new (id, message_id, file_name, mime_type, size, width, height, checksum, created_at)
+SELECT id, message_id, file_name, mime_type, size, width, height, checksum, created_at FROM attachments;
+
+DROP TABLE attachments;
+ALTER TABLE attachments_new RENAME TO attachments;
+
+-- Photos move to the store as well. The old photo columns are cleared when their content is moved.
+ALTER TABLE users ADD COLUMN photo_key TEXT REFERENCES blobs (key);
+ALTER TABLE groups ADD COLUMN photo_key TEXT REFERENCES blobs (key);
+
+-- Reference counting. Deletions cascading from messages and conversations fire the triggers too.
+CREATE TRIGGER attachments_acquire_blob AFTER INSERT ON attachments
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.checksum;
+END;
+
+CREATE TRIGGER attachments_release_blob AFTER DELETE ON attachments
+BEGIN
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.checksum;
+END;
+
+CREATE TRIGGER users_replace_photo_blob AFTER UPDATE OF photo_key ON users WHEN OLD.photo_key IS NOT NEW.photo_key
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.photo_key;
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.photo_key;
+END;
+
+CREATE TRIGGER users_release_photo_blob AFTER DELETE ON users
+BEGIN
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.photo_key;
+END;
+
+CREATE TRIGGER groups_replace_photo_blob AFTER UPDATE OF photo_key ON groups WHEN OLD.photo_key IS NOT NEW.photo_key
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.photo_key;
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.photo_key;
+END;
+
+CREATE TRIGGER groups_release_photo_blob AFTER DELETE ON groups
+BEGIN
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.photo_key;
+END;
@@ -0,0 +1,29 @@
+-- Thumbnails of the images stored as blobs (photos and image attachments), in the sizes generated by the image
+-- pipeline (see service/imaging). Thumbnails are keyed by the blob of the full image, so they are shared by all its
+-- uses, and they are blobs themselves: they are removed when the full image is collected, and their blobs are then
+-- released.
+
+CREATE TABLE thumbnails (
+	blob_key      TEXT    NOT NULL REFERENCES blobs (key) ON DELETE CASCADE,
+	size          INTEGER NOT NULL,
+	thumbnail_key TEXT    NOT NULL REFERENCES blobs (key),
+	mime_type     TEXT    NOT NULL,
+	width         INTEGER NOT NULL,
+	height        INTEGER NOT NULL,
+	PRIMARY KEY (blob_key, size)
+);
+
+CREATE INDEX thumbnails_by_thumbnail ON thumbnails (thumbnail_key);
+
+CREATE TRIGGER thumbnails_acquire_blob AFTER INSERT ON thumbnails
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.thumbnail_key;
+END;
+
+CREATE TRIGGER thumbnails_release_blob AFTER DELETE ON thumbnails
+BEGIN
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.thumbnail_key;
+END;
@@ -0,0 +1,13 @@
+-- Metadata of the user and group photos, to serve them with the right headers: the MIME type of the image (NULL for
+-- the photos uploaded before the image pipeline, which are sniffed when served), and the time of the last change.
+
+ALTER TABLE users ADD COLUMN photo_mime_type TEXT;
+ALTER TABLE users ADD COLUMN photo_updated_at INTEGER;
+ALTER TABLE groups ADD COLUMN photo_mime_type TEXT;
+ALTER TABLE groups ADD COLUMN photo_updated_at INTEGER;
+
+-- The time of the change of existing photos is unknown: they count as changed now
+UPDATE users SET photo_updated_at = CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)
+WHERE photo_key IS NOT NULL OR photo IS NOT NULL;
+UPDATE groups SET photo_updated_at = CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)
+WHERE photo_key IS NOT NULL OR photo IS NOT NULL;
@@ -0,0 +1,42 @@
+-- Full-text search of the messages (requires SQLite with FTS5). The index keeps its own copy of the content, needed for
+-- the snippets, and it's kept in sync with the messages by the triggers below: deleted messages are removed, edited
+-- ones indexed again.
+--
+-- FTS5 rows are identified by integers, while messages have text identifiers (and their implicit rowid may change on
+-- VACUUM): message_search_keys assigns a stable integer to each indexed message.
+
+CREATE TABLE message_search_keys (
+	id         INTEGER NOT NULL PRIMARY KEY,
+	message_id TEXT    NOT NULL UNIQUE
+);
+
+CREATE VIRTUAL TABLE message_search USING fts5(content, tokenize = 'unicode61 remove_diacritics 2');
+
+INSERT INTO message_search_keys (message_id)
+SELECT id FROM messages WHERE content != '' AND deleted_at IS NULL;
+
+INSERT INTO message_search (rowid, content)
+SELECT k.id, m.content FROM message_search_keys k JOIN messages m ON m.id = k.message_id;
+
+CREATE TRIGGER messages_search_insert AFTER INSERT ON messages WHEN NEW.content != '' AND NEW.deleted_at IS NULL
+BEGIN
+	INSERT INTO message_search_keys (message_id) VALUES (NEW.id);
+	INSERT INTO message_search (rowid, content)
+	SELECT id, NEW.content FROM message_search_keys WHERE message_id = NEW.id;
+END;
+
+CREATE TRIGGER messages_search_update AFTER UPDATE OF content, deleted_at ON messages
+BEGIN
+	DELETE FROM message_search WHERE rowid = (SELECT id FROM message_search_keys WHERE message_id = OLD.id);
+	DELETE FROM message_search_keys WHERE message_id = OLD.id;
+	INSERT INTO message_search_keys (message_id)
+	SELECT NEW.id WHERE NEW.content != '' AND NEW.deleted_at IS NULL;
+	INSERT INTO message_search (rowid, content)
+	SELECT id, NEW.content FROM message_search_keys WHERE message_id = NEW.id;
+END;
+
+CREATE TRIGGER messages_search_delete AFTER DELETE ON messages
+BEGIN
+	DELETE FROM message_search WHERE rowid = (SELECT id FROM message_search_keys WHERE message_id = OLD.id);
+	DELETE FROM message_search_keys WHERE message_id = OLD.id;
+END;
@@ -0,0 +1,34 @@
+-- Roles of the group members. Membership itself is still recorded in conversation_members, so a role is removed with
+-- the membership. Every group with members has at least one owner.
+--
+-- Owners can do everything, including managing the admins; admins manage the group (name, photo, members) and can
+-- remove plain members.
+
+CREATE TABLE group_members (
+	group_id TEXT NOT NULL,
+	user_id  TEXT NOT NULL,
+	role     TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+	PRIMARY KEY (group_id, user_id),
+	FOREIGN KEY (group_id, user_id) REFERENCES conversation_members (conversation_id, user_id) ON DELETE CASCADE
+);
+
+-- The creator was the admin of the group: it becomes its owner
+INSERT INTO group_members (group_id, user_id, role)
+SELECT m.conversation_id, m.user_id,
+	CASE WHEN m.user_id = g.created_by THEN 'owner' WHEN m.is_admin THEN 'admin' ELSE 'member' END
+FROM conversation_members m
+JOIN groups g ON g.id = m.conversation_id;
+
+-- If the creator left, the oldest admin becomes the owner, or the oldest member if there are no admins
+UPDATE group_members SET role = 'owner'
+WHERE NOT EXISTS(SELECT 1 FROM group_members o WHERE o.group_id = group_members.group_id AND o.role = 'owner')
+	AND user_id = (
+		SELECT gm.user_id
+		FROM group_members gm
+		JOIN conversation_members m ON m.conversation_id = gm.group_id AND m.user_id = gm.user_id
+		WHERE gm.group_id = group_members.group_id
+		ORDER BY gm.role = 'admin' DESC, m.joined_at, m.user_id
+		LIMIT 1
+	);
+
+ALTER TABLE conversation_members DROP COLUMN is_admin;
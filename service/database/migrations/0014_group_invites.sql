@@ -0,0 +1,15 @@
+-- Invite links to join a group. An invite stops working when it expires (if expires_at is set), when it has been used
+-- max_uses times (if set), or when it's revoked; it's kept afterwards, so that admins can see how it was used.
+
+CREATE TABLE group_invites (
+	token      TEXT    NOT NULL PRIMARY KEY,
+	group_id   TEXT    NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	created_by TEXT    REFERENCES users (id) ON DELETE SET NULL,
+	created_at INTEGER NOT NULL,
+	expires_at INTEGER,
+	max_uses   INTEGER CHECK (max_uses > 0),
+	uses       INTEGER NOT NULL DEFAULT 0,
+	revoked_at INTEGER
+);
+
+CREATE INDEX group_invites_by_group ON group_invites (group_id, created_at);
@@ -0,0 +1,14 @@
+-- System messages record the changes to a conversation (e.g., a member joining) in its history. They are stored as
+-- messages of kind 'system', sent by the user who made the change, without content: what happened is described by
+-- their row in system_messages. They don't count as unread, and can't be edited, deleted, forwarded, replied to or
+-- reacted to.
+
+ALTER TABLE messages ADD COLUMN kind TEXT NOT NULL DEFAULT 'text' CHECK (kind IN ('text', 'system'));
+
+CREATE TABLE system_messages (
+	message_id TEXT NOT NULL PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+	action     TEXT NOT NULL,
+	target_id  TEXT REFERENCES users (id) ON DELETE SET NULL,
+	old_value  TEXT,
+	new_value  TEXT
+);
@@ -0,0 +1,29 @@
+-- Reactions are emoji, and users can react to a message with several distinct emoji: the emoji is part of the key.
+-- Reactions recorded before were words (e.g., "like"), mapped to the matching emoji.
+
+CREATE TABLE reactions_new (
+	message_id TEXT    NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	user_id    TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	emoji      TEXT    NOT NULL,
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (message_id, user_id, emoji)
+);
+
+INSERT INTO reactions_new (message_id, user_id, emoji, created_at)
+SELECT message_id, user_id,
+	CASE lower(type)
+		WHEN 'love' THEN '❤️'
+		WHEN 'heart' THEN '❤️'
+		WHEN 'laugh' THEN '😂'
+		WHEN 'haha' THEN '😂'
+		WHEN 'wow' THEN '😮'
+		WHEN 'sad' THEN '😢'
+		WHEN 'angry' THEN '😡'
+		WHEN 'dislike' THEN '👎'
+		ELSE '👍'
+	END,
+	created_at
+FROM reactions;
+
+DROP TABLE reactions;
+ALTER TABLE reactions_new RENAME TO reactions;
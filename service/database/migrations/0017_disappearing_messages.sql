@@ -0,0 +1,9 @@
+-- Disappearing messages. The timer of a conversation applies to the messages sent while it's set: each of them expires
+-- when the timer runs out, and is then deleted with its attachment. System messages never expire.
+
+ALTER TABLE conversations ADD COLUMN message_timer TEXT NOT NULL DEFAULT 'off'
+	CHECK (message_timer IN ('off', '1h', '24h', '7d'));
+
+ALTER TABLE messages ADD COLUMN expires_at INTEGER;
+
+CREATE INDEX messages_by_expiry ON messages (expires_at) WHERE expires_at IS NOT NULL;
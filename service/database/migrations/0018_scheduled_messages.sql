@@ -0,0 +1,35 @@
+-- Messages scheduled by their sender, to be sent later. They are kept apart from the messages until they are sent, so
+-- that the other members can't see them. The attachment, if any, is stored inline, and holds a reference to its blob
+-- until the message is sent (the message then holds its own) or cancelled.
+
+CREATE TABLE scheduled_messages (
+	id              TEXT    NOT NULL PRIMARY KEY,
+	conversation_id TEXT    NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+	sender_id       TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	content         TEXT    NOT NULL,
+	reply_to        TEXT    REFERENCES messages (id) ON DELETE SET NULL,
+	file_name       TEXT,
+	mime_type       TEXT,
+	size            INTEGER,
+	width           INTEGER,
+	height          INTEGER,
+	checksum        TEXT    REFERENCES blobs (key),
+	send_at         INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL
+);
+
+CREATE INDEX scheduled_messages_by_time ON scheduled_messages (send_at);
+CREATE INDEX scheduled_messages_by_sender ON scheduled_messages (sender_id, send_at, id);
+
+CREATE TRIGGER scheduled_messages_acquire_blob AFTER INSERT ON scheduled_messages WHEN NEW.checksum IS NOT NULL
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.checksum;
+END;
+
+CREATE TRIGGER scheduled_messages_release_blob AFTER DELETE ON scheduled_messages WHEN OLD.checksum IS NOT NULL
+BEGIN
+	UPDATE blobs
+	SET refcount    = refcount - 1,
+		released_at = CASE WHEN refcount = 1 THEN CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER) END
+	WHERE key = OLD.checksum;
+END;
@@ -0,0 +1,20 @@
+-- Pinned messages, shown to all the members of their conversation, and starred messages, private to each user.
+
+CREATE TABLE pinned_messages (
+	message_id      TEXT    NOT NULL PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+	conversation_id TEXT    NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+	pinned_by       TEXT    REFERENCES users (id) ON DELETE SET NULL,
+	pinned_at       INTEGER NOT NULL
+);
+
+CREATE INDEX pinned_messages_by_conversation ON pinned_messages (conversation_id, pinned_at);
+
+CREATE TABLE starred_messages (
+	user_id    TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	message_id TEXT    NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	starred_at INTEGER NOT NULL,
+	PRIMARY KEY (user_id, message_id)
+);
+
+CREATE INDEX starred_messages_by_time ON starred_messages (user_id, starred_at, message_id);
+CREATE INDEX starred_messages_by_message ON starred_messages (message_id);
@@ -0,0 +1,15 @@
+-- Mentions of group members in messages (`@name`), stored by user so that they survive renames. start and length
+-- locate the mention in the content, in UTF-16 code units (as JavaScript strings are indexed).
+
+CREATE TABLE message_mentions (
+	message_id TEXT    NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	user_id    TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	start      INTEGER NOT NULL CHECK (start >= 0),
+	length     INTEGER NOT NULL CHECK (length > 1),
+	PRIMARY KEY (message_id, start)
+);
+
+CREATE INDEX message_mentions_by_user ON message_mentions (user_id, message_id);
+
+-- Number of unread messages mentioning the member, maintained like unread_count.
+ALTER TABLE conversation_members ADD COLUMN mention_count INTEGER NOT NULL DEFAULT 0;
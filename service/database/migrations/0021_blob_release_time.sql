@@ -0,0 +1,48 @@
+-- The release time of blobs was taken from the SQLite clock by the triggers, while every other time (including the
+-- cutoff of the garbage collector) comes from the application. The triggers now only drop the references: a blob with
+-- no references and no release time has been released, and CollectBlobs stamps it with the application time when it
+-- first sees it. The grace period starts at most one collection later, never earlier.
+
+DROP TRIGGER attachments_release_blob;
+CREATE TRIGGER attachments_release_blob AFTER DELETE ON attachments
+BEGIN
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.checksum;
+END;
+
+DROP TRIGGER users_replace_photo_blob;
+CREATE TRIGGER users_replace_photo_blob AFTER UPDATE OF photo_key ON users WHEN OLD.photo_key IS NOT NEW.photo_key
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.photo_key;
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.photo_key;
+END;
+
+DROP TRIGGER users_release_photo_blob;
+CREATE TRIGGER users_release_photo_blob AFTER DELETE ON users
+BEGIN
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.photo_key;
+END;
+
+DROP TRIGGER groups_replace_photo_blob;
+CREATE TRIGGER groups_replace_photo_blob AFTER UPDATE OF photo_key ON groups WHEN OLD.photo_key IS NOT NEW.photo_key
+BEGIN
+	UPDATE blobs SET refcount = refcount + 1, released_at = NULL WHERE key = NEW.photo_key;
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.photo_key;
+END;
+
+DROP TRIGGER groups_release_photo_blob;
+CREATE TRIGGER groups_release_photo_blob AFTER DELETE ON groups
+BEGIN
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.photo_key;
+END;
+
+DROP TRIGGER thumbnails_release_blob;
+CREATE TRIGGER thumbnails_release_blob AFTER DELETE ON thumbnails
+BEGIN
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.thumbnail_key;
+END;
+
+DROP TRIGGER scheduled_messages_release_blob;
+CREATE TRIGGER scheduled_messages_release_blob AFTER DELETE ON scheduled_messages WHEN OLD.checksum IS NOT NULL
+BEGIN
+	UPDATE blobs SET refcount = refcount - 1 WHERE key = OLD.checksum;
+END;
@@ -0,0 +1,11 @@
+-- Users blocked by other users. A user who blocked someone is hidden from their searches of the user directory.
+
+CREATE TABLE user_blocks (
+	user_id    TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	blocked_id TEXT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (user_id, blocked_id),
+	CHECK (user_id != blocked_id)
+);
+
+CREATE INDEX user_blocks_by_blocked ON user_blocks (blocked_id, user_id);
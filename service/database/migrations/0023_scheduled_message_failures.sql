@@ -0,0 +1,7 @@
+-- A scheduled message that can't be sent is kept with the time of the failure, instead of being tried again at every
+-- round (and holding back the messages due after it). Its sender still sees it, and can cancel it.
+
+ALTER TABLE scheduled_messages ADD COLUMN failed_at INTEGER;
+
+DROP INDEX scheduled_messages_by_time;
+CREATE INDEX scheduled_messages_by_time ON scheduled_messages (send_at) WHERE failed_at IS NULL;
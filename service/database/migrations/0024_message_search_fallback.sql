@@ -0,0 +1,8 @@
+-- Message search without FTS5: migration 0012 is skipped when SQLite lacks the extension, and the full-text index is
+-- created later, once the database is opened with it (see search-index.go). The keys of the index are needed then, so
+-- they are created here if 0012 didn't.
+
+CREATE TABLE IF NOT EXISTS message_search_keys (
+	id         INTEGER NOT NULL PRIMARY KEY,
+	message_id TEXT    NOT NULL UNIQUE
+);
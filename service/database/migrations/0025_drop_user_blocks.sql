@@ -0,0 +1,3 @@
+-- Users can't block each other after all: the table of migration 0022 is dropped.
+
+DROP TABLE user_blocks;
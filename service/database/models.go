@@ -0,0 +1,311 @@
+package database
+
+import "time"
+
+// User is a registered user.
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DirectoryUser is a user found by SearchUsers. HasPhoto tells whether they have a profile photo.
+type DirectoryUser struct {
+	User
+	HasPhoto bool
+}
+
+// Conversation is a chat the user is a member of: either a 1:1 conversation or a group. For 1:1 conversations, Name
+// is the name of the other participant. Timestamp is the time of the latest activity: the last message, or the creation
+// of the conversation. LastMessage is a preview of the last message, empty if there are none. MessageTimer is how long
+// the messages sent to the conversation are kept. MentionCount is the number of unread messages mentioning the user.
+// Activity is the time the conversation is sorted by in a page of the list: Timestamp, or the time of the latest
+// activity as of the snapshot of the page (see Cursor).
+type Conversation struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	IsGroup           bool      `json:"isGroup"`
+	LastMessage       string    `json:"lastMessage"`
+	LastMessageSender string    `json:"lastMessageSender,omitempty"`
+	UnreadCount       int       `json:"unreadCount"`
+	MentionCount      int       `json:"mentionCount"`
+	Timestamp         time.Time `json:"timestamp"`
+	MessageTimer      string    `json:"messageTimer"`
+	Activity          time.Time `json:"-"`
+}
+
+// Timers of disappearing messages: each message sent while a timer is set expires when the timer runs out, and is
+// then deleted.
+const (
+	TimerOff  = "off"
+	TimerHour = "1h"
+	TimerDay  = "24h"
+	TimerWeek = "7d"
+)
+
+// messageTimers maps the timers to how long the messages are kept.
+var messageTimers = map[string]time.Duration{
+	TimerOff:  0,
+	TimerHour: time.Hour,
+	TimerDay:  24 * time.Hour,
+	TimerWeek: 7 * 24 * time.Hour,
+}
+
+// ValidTimer reports whether timer is one of the timers of disappearing messages.
+func ValidTimer(timer string) bool {
+	_, ok := messageTimers[timer]
+	return ok
+}
+
+// Delivery statuses of a message, aggregated over its recipients.
+const (
+	// MessageSent messages are stored, but not yet received by all recipients.
+	MessageSent = "sent"
+
+	// MessageReceived messages have been received by all recipients (one check).
+	MessageReceived = "received"
+
+	// MessageRead messages have been read by all recipients (two checks).
+	MessageRead = "read"
+)
+
+// Message is a message sent to a conversation. Deleted messages are kept as placeholders, without content. Messages
+// sent while the conversation had a timer are deleted for good at ExpiresAt. Pinned messages are shown to all the
+// members of the conversation, while Starred tells whether the user who fetched the message starred it.
+type Message struct {
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversationId"`
+	Kind           string          `json:"kind"`
+	SenderID       string          `json:"senderId"`
+	Sender         string          `json:"sender"`
+	Content        string          `json:"content"`
+	System         *SystemEvent    `json:"system,omitempty"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Forwarded      bool            `json:"forwarded"`
+	Deleted        bool            `json:"deleted"`
+	Edited         bool            `json:"edited"`
+	EditedAt       *time.Time      `json:"editedAt,omitempty"`
+	Status         string          `json:"status"`
+	ReplyTo        *Quote          `json:"replyTo,omitempty"`
+	Attachment     *Attachment     `json:"attachment,omitempty"`
+	Mentions       []Mention       `json:"mentions"`
+	Reactions      []ReactionCount `json:"reactions"`
+	ExpiresAt      *time.Time      `json:"expiresAt,omitempty"`
+	Pinned         bool            `json:"pinned"`
+	Starred        bool            `json:"starred"`
+}
+
+// Mention is a mention of a group member in the content of a message, as `@name`. Offset and Length locate it in the
+// content, in UTF-16 code units; Name is the current name of the user, who may have been renamed since.
+type Mention struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// Kinds of messages. System messages record a change to the conversation, made by their sender: they have no content,
+// the change is described by their SystemEvent.
+const (
+	MessageText   = "text"
+	MessageSystem = "system"
+)
+
+// Actions recorded by system messages.
+const (
+	// ActionMemberJoined: the sender joined the group with an invite
+	ActionMemberJoined = "member.joined"
+
+	// ActionMemberAdded: the sender added the target to the group
+	ActionMemberAdded = "member.added"
+
+	// ActionMemberRemoved: the sender removed the target from the group
+	ActionMemberRemoved = "member.removed"
+
+	// ActionMemberLeft: the sender left the group
+	ActionMemberLeft = "member.left"
+
+	// ActionGroupRenamed: the sender renamed the group from OldValue to NewValue
+	ActionGroupRenamed = "group.renamed"
+
+	// ActionGroupPhotoChanged: the sender changed the photo of the group
+	ActionGroupPhotoChanged = "group.photo_changed"
+
+	// ActionTimerChanged: the sender changed the timer of disappearing messages from OldValue to NewValue
+	ActionTimerChanged = "conversation.timer_changed"
+)
+
+// SystemEvent is the change recorded by a system message. TargetID (and Target, their name) is the user affected by the
+// change, if not the sender; OldValue and NewValue are set for changes of a value.
+type SystemEvent struct {
+	Action   string `json:"action"`
+	TargetID string `json:"targetId,omitempty"`
+	Target   string `json:"target,omitempty"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+// ScheduledMessage is a message to be sent by the user who scheduled it at SendAt, or as soon as possible after it. Until
+// then, it's visible only to them. Its attachment has no identifier yet. FailedAt is set if sending it failed: it's not
+// tried again.
+type ScheduledMessage struct {
+	ID             string      `json:"id"`
+	ConversationID string      `json:"conversationId"`
+	SenderID       string      `json:"senderId"`
+	Content        string      `json:"content"`
+	ReplyTo        string      `json:"replyTo,omitempty"`
+	Attachment     *Attachment `json:"attachment,omitempty"`
+	SendAt         time.Time   `json:"sendAt"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	FailedAt       *time.Time  `json:"failedAt,omitempty"`
+}
+
+// ExpiredMessage is a message deleted by DeleteExpiredMessages.
+type ExpiredMessage struct {
+	ID             string
+	ConversationID string
+}
+
+// NewMessage is a message to be sent with SaveMessage. Content may be empty if there is an attachment.
+type NewMessage struct {
+	ConversationID string
+
+	Content string
+
+	// ReplyTo is the identifier of the message replied to, if any
+	ReplyTo string
+
+	// Attachment is the file attached to the message, if any. Its identifier is assigned when the message is saved.
+	// The content is not saved in the database: the caller stores it in the blob store, with Checksum as the key, after
+	// registering it with RegisterBlob.
+	Attachment *Attachment
+}
+
+// Attachment is the metadata of a file attached to a message. Width and Height are set only for images.
+type Attachment struct {
+	ID       string `json:"id"`
+	FileName string `json:"fileName"`
+	MIMEType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Checksum string `json:"checksum"`
+}
+
+// Photo is the photo of a user or a group, stored as the blob Key. MIMEType is empty for photos uploaded by older
+// versions, whose type is unknown.
+type Photo struct {
+	Key       string
+	MIMEType  string
+	UpdatedAt time.Time
+}
+
+// Thumbnail is a reduced copy of an image stored as a blob. Size is the longest side requested for the thumbnail, Key
+// the blob with its content.
+type Thumbnail struct {
+	Size     int
+	Key      string
+	MIMEType string
+	Width    int
+	Height   int
+}
+
+// Quote is a snapshot of the message another message replies to, enough to render it without fetching it. Excerpt is
+// truncated as the previews in the conversation list, and empty if the message has been deleted.
+type Quote struct {
+	ID       string `json:"id"`
+	SenderID string `json:"senderId"`
+	Sender   string `json:"sender"`
+	Excerpt  string `json:"excerpt"`
+	Deleted  bool   `json:"deleted"`
+}
+
+// Revision is a version of the content of a message. Timestamp is the time it was set: when the message was sent, for
+// the first revision, and when it was edited, for the others.
+type Revision struct {
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SearchResult is a message matching a search. Snippet is an excerpt of its content around the matches, as HTML text
+// with the matching words wrapped in <mark> elements.
+type SearchResult struct {
+	Message Message `json:"message"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"-"`
+}
+
+// MaxPinnedMessages is the maximum number of messages pinned in a conversation.
+const MaxPinnedMessages = 5
+
+// Pin is a message pinned in its conversation by PinnedBy (empty if they deleted their account).
+type Pin struct {
+	Message  Message   `json:"message"`
+	PinnedBy string    `json:"pinnedBy,omitempty"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}
+
+// StarredMessage is a message starred by a user.
+type StarredMessage struct {
+	Message   Message   `json:"message"`
+	StarredAt time.Time `json:"starredAt"`
+}
+
+// Reaction is the reaction of a user to a message with an emoji.
+type Reaction struct {
+	MessageID string    `json:"messageId"`
+	UserID    string    `json:"userId"`
+	Emoji     string    `json:"emoji"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReactionCount is the number of users who reacted to a message with an emoji. Reacted tells whether the user who
+// fetched the message is one of them.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// Group is a conversation with a name, a photo and more than two members.
+type Group struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	CreatedBy string        `json:"createdBy"`
+	Members   []GroupMember `json:"members"`
+}
+
+// Roles of the members of a group, from the most to the least privileged. Owners can do everything, including
+// managing the admins; admins can rename the group, change its photo, add members and remove plain members.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// GroupMember is a member of a group with their role.
+type GroupMember struct {
+	User
+	Role string `json:"role"`
+}
+
+// Invite is a link to join a group, identified by its token. It stops working once ExpiresAt has passed (if set), once
+// it has been used MaxUses times (if not 0), or once revoked.
+type Invite struct {
+	Token     string     `json:"token"`
+	GroupID   string     `json:"groupId"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	MaxUses   int        `json:"maxUses,omitempty"`
+	Uses      int        `json:"uses"`
+	Revoked   bool       `json:"revoked"`
+}
+
+// Receipt is the progress of a member through a conversation: the messages sent up to ReceivedUpTo have been received,
+// those up to ReadUpTo have been read. The Unix epoch means nothing yet.
+type Receipt struct {
+	ConversationID string    `json:"conversationId"`
+	UserID         string    `json:"userId"`
+	ReceivedUpTo   time.Time `json:"receivedUpTo"`
+	ReadUpTo       time.Time `json:"readUpTo"`
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MoveLegacyBlobs moves to the blob store the attachments saved in legacy_blobs, and the photos saved in the photo
+// column of users and groups, by the versions before the blob store. Each blob is moved in its own transaction, so an
+// interrupted move resumes where it stopped. It's meant to be called at startup, before serving requests.
+func (db *appdbimpl) MoveLegacyBlobs(ctx context.Context, put func(ctx context.Context, key string, r io.Reader, size int64) error) (int, error) {
+	moved := 0
+
+	// Attachments: the blobs are already registered and referenced
+	for {
+		var key string
+		var data []byte
+		err := db.c.QueryRowContext(ctx, "SELECT key, data FROM legacy_blobs LIMIT 1").Scan(&key, &data)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		} else if err != nil {
+			return moved, err
+		}
+
+		if err := put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+			return moved, fmt.Errorf("moving attachment %s: %w", key, err)
+		}
+		if _, err := db.c.ExecContext(ctx, "DELETE FROM legacy_blobs WHERE key = ?", key); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+
+	// Photos
+	for _, table := range []string{"users", "groups"} {
+		for {
+			var id string
+			var data []byte
+			err := db.c.QueryRowContext(ctx, "SELECT id, photo FROM "+table+" WHERE photo IS NOT NULL LIMIT 1").
+				Scan(&id, &data)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			} else if err != nil {
+				return moved, err
+			}
+
+			sum := sha256.Sum256(data)
+			key := hex.EncodeToString(sum[:])
+			if err := db.RegisterBlob(ctx, key, int64(len(data))); err != nil {
+				return moved, err
+			}
+			if err := put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+				return moved, fmt.Errorf("moving photo of %s %s: %w", table, id, err)
+			}
+			// The type of these photos is unknown, and their time of change is kept
+			_, err = db.c.ExecContext(ctx, "UPDATE "+table+" SET photo_key = ?, photo = NULL WHERE id = ?", key, id)
+			if err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+	return moved, nil
+}
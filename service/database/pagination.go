@@ -0,0 +1,83 @@
+package database
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the number of items returned when Page.Limit is not set.
+	DefaultPageSize = 50
+
+	// MaxPageSize is the maximum number of items returned in a page.
+	MaxPageSize = 200
+)
+
+// Cursor is a position in a list sorted by timestamp, with the identifier to break ties. Being based on values and not
+// on offsets, cursors stay valid while items are added to the list.
+//
+// In lists sorted by a timestamp that changes, like the conversations sorted by activity, items would move across
+// pages while clients walk through them. Snapshot, if set on the Before cursor, is the time the first page was fetched:
+// the older pages are then taken from the list as it was at that time, so that no item is skipped or repeated. Items
+// that changed since are at the top of the current list, where clients polling with After find them.
+type Cursor struct {
+	Timestamp time.Time
+	ID        string
+	Snapshot  time.Time
+}
+
+// Page selects a slice of a list. Items strictly before Before and strictly after After are returned; if both are
+// nil, the page starts from the most recent item. Without After, the page is taken from the most recent end of the
+// range (i.e., walking backward in time), otherwise from its oldest end (walking forward).
+type Page struct {
+	Before *Cursor
+	After  *Cursor
+	Limit  int
+}
+
+// Forward reports whether the page walks forward in time (from After), instead of backward.
+func (p Page) Forward() bool {
+	return p.After != nil
+}
+
+// size returns the limit of the page, clamped to [1, MaxPageSize].
+func (p Page) size() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageSize
+	case p.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return p.Limit
+	}
+}
+
+// where returns the conditions selecting the page on the given timestamp and id columns, with their arguments.
+func (p Page) where(timestampColumn string, idColumn string) (string, []interface{}) {
+	var cond string
+	var args []interface{}
+	if p.Before != nil {
+		cond += " AND (" + timestampColumn + ", " + idColumn + ") < (?, ?)"
+		args = append(args, p.Before.Timestamp.UnixMilli(), p.Before.ID)
+	}
+	if p.After != nil {
+		cond += " AND (" + timestampColumn + ", " + idColumn + ") > (?, ?)"
+		args = append(args, p.After.Timestamp.UnixMilli(), p.After.ID)
+	}
+	return cond, args
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses for the page. One more item than the page size is selected, so that
+// callers can tell whether there are more items (see hasMore).
+func (p Page) orderBy(timestampColumn string, idColumn string) string {
+	dir := "DESC"
+	if p.Forward() {
+		dir = "ASC"
+	}
+	return " ORDER BY " + timestampColumn + " " + dir + ", " + idColumn + " " + dir + " LIMIT " + strconv.Itoa(p.size()+1)
+}
+
+// hasMore reports whether n items, selected with orderBy, include the extra one signaling more items after the page.
+func (p Page) hasMore(n int) bool {
+	return n > p.size()
+}
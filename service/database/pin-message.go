@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PinMessage pins a message of a conversation the user is a member of (an admin of, for groups), and returns it.
+// changed is false if the message was already pinned. Deleted messages and system messages can't be pinned, and
+// ErrConflict is returned if the conversation already has MaxPinnedMessages pinned messages.
+func (db *appdbimpl) PinMessage(ctx context.Context, userID string, messageID string) (message Message, changed bool, err error) {
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		message, err = getPinnableMessage(ctx, tx, userID, messageID)
+		if err != nil || message.Pinned {
+			return err
+		}
+
+		var pinned int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM pinned_messages WHERE conversation_id = ?", message.ConversationID).
+			Scan(&pinned)
+		if err != nil {
+			return err
+		} else if pinned >= MaxPinnedMessages {
+			return fmt.Errorf("conversation %s has %d pinned messages already: %w", message.ConversationID, pinned, ErrConflict)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO pinned_messages (message_id, conversation_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?)`,
+			messageID, message.ConversationID, userID, now())
+		if err != nil {
+			return err
+		}
+		message.Pinned, changed = true, true
+		return nil
+	})
+	return message, changed, err
+}
+
+// getPinnableMessage returns a message the user can pin or unpin: a message of a conversation they are a member of (an
+// admin of, for groups), neither deleted nor a system message.
+func getPinnableMessage(ctx context.Context, tx *sql.Tx, userID string, messageID string) (Message, error) {
+	message, err := getVisibleMessage(ctx, tx, userID, messageID)
+	if err != nil {
+		return message, err
+	} else if message.Deleted {
+		return message, fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+	} else if message.Kind == MessageSystem {
+		return message, fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+	}
+
+	var isGroup bool
+	err = tx.QueryRowContext(ctx, "SELECT is_group FROM conversations WHERE id = ?", message.ConversationID).Scan(&isGroup)
+	if err != nil {
+		return message, err
+	} else if isGroup {
+		err = checkGroupAdmin(ctx, tx, message.ConversationID, userID)
+	}
+	return message, err
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PromoteGroupMember raises the role of memberID in a group by one step (member to admin, admin to owner), and
+// returns the new role. Admins can promote members to admins, only owners can make other owners. ErrConflict is
+// returned if memberID is already an owner.
+func (db *appdbimpl) PromoteGroupMember(ctx context.Context, userID string, groupID string, memberID string) (string, error) {
+	var newRole string
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		role, memberRole, err := memberRoles(ctx, tx, groupID, userID, memberID)
+		if err != nil {
+			return err
+		}
+
+		switch memberRole {
+		case RoleMember:
+			newRole = RoleAdmin
+		case RoleAdmin:
+			newRole = RoleOwner
+		default:
+			return fmt.Errorf("user %s is already an owner: %w", memberID, ErrConflict)
+		}
+		if roleRank(role) < roleRank(newRole) {
+			return fmt.Errorf("not allowed to promote members to %s: %w", newRole, ErrForbidden)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?",
+			newRole, groupID, memberID)
+		return err
+	})
+	return newRole, err
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// memberRole returns the role of a member of a group.
+func memberRole(t *testing.T, db *appdbimpl, groupID string, userID string) string {
+	t.Helper()
+	role, err := groupRole(context.Background(), db.c, groupID, userID)
+	if err != nil {
+		t.Fatalf("role of %s: %v", userID, err)
+	}
+	return role
+}
+
+func TestGroupRoles(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	owner, admin, bob := createUser(t, db, "owner"), createUser(t, db, "admin"), createUser(t, db, "bob")
+	carol, eve := createUser(t, db, "carol"), createUser(t, db, "eve")
+	group, err := db.CreateGroup(ctx, owner.ID, "friends", []string{admin.ID, bob.ID, carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain members can't promote anyone, admins can make admins but not owners
+	if _, err := db.PromoteGroupMember(ctx, bob.ID, group.ID, carol.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("promotion by a plain member = %v, want ErrForbidden", err)
+	}
+	if role, err := db.PromoteGroupMember(ctx, owner.ID, group.ID, admin.ID); err != nil || role != RoleAdmin {
+		t.Fatalf("PromoteGroupMember = %q, %v, want admin", role, err)
+	}
+	if role, err := db.PromoteGroupMember(ctx, admin.ID, group.ID, bob.ID); err != nil || role != RoleAdmin {
+		t.Errorf("promotion to admin by an admin = %q, %v, want admin", role, err)
+	}
+	if _, err := db.PromoteGroupMember(ctx, admin.ID, group.ID, bob.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("promotion to owner by an admin = %v, want ErrForbidden", err)
+	}
+	if _, err := db.PromoteGroupMember(ctx, owner.ID, group.ID, owner.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("promotion of an owner = %v, want ErrConflict", err)
+	}
+	if _, err := db.PromoteGroupMember(ctx, eve.ID, group.ID, carol.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("promotion by a non-member = %v, want ErrForbidden", err)
+	}
+	if _, err := db.PromoteGroupMember(ctx, owner.ID, group.ID, eve.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("promotion of a non-member = %v, want ErrNotFound", err)
+	}
+
+	// An admin can't demote or remove the owner, nor another admin
+	if _, err := db.DemoteGroupMember(ctx, admin.ID, group.ID, owner.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("demotion of the owner by an admin = %v, want ErrForbidden", err)
+	}
+	if _, err := db.RemoveGroupMember(ctx, admin.ID, group.ID, owner.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("removal of the owner by an admin = %v, want ErrForbidden", err)
+	}
+	if _, err := db.DemoteGroupMember(ctx, admin.ID, group.ID, bob.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("demotion of an admin by an admin = %v, want ErrForbidden", err)
+	}
+	if _, err := db.RemoveGroupMember(ctx, admin.ID, group.ID, bob.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("removal of an admin by an admin = %v, want ErrForbidden", err)
+	}
+	if role := memberRole(t, db, group.ID, owner.ID); role != RoleOwner {
+		t.Errorf("owner is now %s", role)
+	}
+
+	// But admins can demote themselves, and remove plain members
+	if role, err := db.DemoteGroupMember(ctx, bob.ID, group.ID, bob.ID); err != nil || role != RoleMember {
+		t.Errorf("demotion of oneself = %q, %v, want member", role, err)
+	}
+	if _, err := db.DemoteGroupMember(ctx, owner.ID, group.ID, bob.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("demotion of a plain member = %v, want ErrConflict", err)
+	}
+	m, err := db.RemoveGroupMember(ctx, admin.ID, group.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.System == nil || m.System.Action != ActionMemberRemoved || m.System.TargetID != bob.ID {
+		t.Errorf("removal message = %+v, want bob removed", m.System)
+	}
+	if _, err := db.RemoveGroupMember(ctx, carol.ID, group.ID, admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("removal by a plain member = %v, want ErrForbidden", err)
+	}
+	if _, err := db.RemoveGroupMember(ctx, admin.ID, group.ID, admin.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("removal of oneself = %v, want ErrConflict", err)
+	}
+
+	// The last owner can't be demoted, even by themselves; owners can demote each other
+	if _, err := db.DemoteGroupMember(ctx, owner.ID, group.ID, owner.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("demotion of the last owner = %v, want ErrConflict", err)
+	}
+	if role, err := db.PromoteGroupMember(ctx, owner.ID, group.ID, admin.ID); err != nil || role != RoleOwner {
+		t.Fatalf("promotion to owner = %q, %v, want owner", role, err)
+	}
+	if role, err := db.DemoteGroupMember(ctx, admin.ID, group.ID, owner.ID); err != nil || role != RoleAdmin {
+		t.Errorf("demotion of an owner by an owner = %q, %v, want admin", role, err)
+	}
+	if _, err := db.RemoveGroupMember(ctx, admin.ID, group.ID, owner.ID); err != nil {
+		t.Errorf("removal of an admin by an owner: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package database
+
+import "context"
+
+// RegisterBlob records a blob about to be stored. A new blob has no references: it's released right away, and it must
+// be referenced within the collection grace period. If the blob exists and has no references, its release time is
+// refreshed, so that it's not collected while it's being reused. If CollectBlobs is removing the same content, it
+// waits until the content is gone from the store, to be uploaded again.
+func (db *appdbimpl) RegisterBlob(ctx context.Context, key string, size int64) error {
+	for {
+		db.collectingMu.Lock()
+		done, collecting := db.collecting[key]
+		if !collecting {
+			_, err := db.c.ExecContext(ctx, `
+				INSERT INTO blobs (key, size, refcount, released_at) VALUES (?, ?, 0, ?)
+				ON CONFLICT (key) DO UPDATE SET released_at = excluded.released_at WHERE refcount = 0`, key, size, now())
+			db.collectingMu.Unlock()
+			return err
+		}
+		db.collectingMu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RemoveGroupMember removes memberID from a group. Admins can remove plain members, owners can remove anyone. Users
+// can't remove themselves (ErrConflict): they leave the group instead. The system message recording the removal is
+// returned.
+func (db *appdbimpl) RemoveGroupMember(ctx context.Context, userID string, groupID string, memberID string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		role, memberRole, err := memberRoles(ctx, tx, groupID, userID, memberID)
+		if err != nil {
+			return err
+		}
+		if memberID == userID {
+			return fmt.Errorf("members can't remove themselves, they leave the group: %w", ErrConflict)
+		}
+		if role != RoleOwner && roleRank(role) <= roleRank(memberRole) {
+			return fmt.Errorf("not allowed to remove members with role %s: %w", memberRole, ErrForbidden)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM conversation_members WHERE conversation_id = ? AND user_id = ?",
+			groupID, memberID)
+		if err != nil {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, groupID, userID, SystemEvent{
+			Action:   ActionMemberRemoved,
+			TargetID: memberID,
+		})
+		return err
+	})
+	return message, err
+}
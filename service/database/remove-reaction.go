@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RemoveReaction removes the reaction of the user to a message with an emoji. ErrNotFound is returned if the user did
+// not react with it.
+func (db *appdbimpl) RemoveReaction(ctx context.Context, userID string, messageID string, emoji string) error {
+	return db.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := getVisibleMessage(ctx, tx, userID, messageID); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM reactions WHERE message_id = ? AND user_id = ? AND emoji = ?",
+			messageID, userID, emoji)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return fmt.Errorf("reaction %s to message %s: %w", emoji, messageID, ErrNotFound)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RevokeGroupInvite revokes an invite to a group the user is an admin of. Revoking an invite twice does nothing.
+func (db *appdbimpl) RevokeGroupInvite(ctx context.Context, userID string, groupID string, token string) error {
+	return db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupAdmin(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE group_invites SET revoked_at = COALESCE(revoked_at, ?) WHERE token = ? AND group_id = ?`,
+			now(), token, groupID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("invite %s: %w", token, ErrNotFound)
+		}
+		return nil
+	})
+}
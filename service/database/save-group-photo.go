@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SaveGroupPhoto replaces the photo of a group the user is an admin of with the blob photoKey, which must have been
+// registered, and returns the system message recording it.
+func (db *appdbimpl) SaveGroupPhoto(ctx context.Context, userID string, groupID string, photoKey string, mimeType string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupAdmin(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE groups SET photo_key = ?, photo_mime_type = ?, photo_updated_at = ? WHERE id = ?`,
+			photoKey, mimeType, now(), groupID)
+		if err != nil {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, groupID, userID, SystemEvent{Action: ActionGroupPhotoChanged})
+		return err
+	})
+	return message, err
+}
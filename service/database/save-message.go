@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SaveMessage sends a new message to a conversation the user is a member of. If m.ReplyTo is not empty, the message is
+// a reply to that message, which must be a text message of the same conversation, not deleted (ErrNotFound otherwise).
+func (db *appdbimpl) SaveMessage(ctx context.Context, userID string, m NewMessage) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkMember(ctx, tx, m.ConversationID, userID); err != nil {
+			return err
+		}
+
+		if err := checkReply(ctx, tx, m); err != nil {
+			return err
+		}
+
+		var err error
+		message, err = insertMessage(ctx, tx, userID, m, false)
+		return err
+	})
+	return message, err
+}
+
+// checkReply returns ErrNotFound if m is a reply to a message that can't be replied to: one of another conversation,
+// a deleted or expired message, or a system message.
+func checkReply(ctx context.Context, q querier, m NewMessage) error {
+	if m.ReplyTo == "" {
+		return nil
+	}
+
+	var exists bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM messages m
+			WHERE m.id = ? AND m.conversation_id = ? AND m.kind = ? AND m.deleted_at IS NULL`+notExpired+`
+		)`, m.ReplyTo, m.ConversationID, MessageText, now()).Scan(&exists)
+	if err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("replied message %s in conversation %s: %w", m.ReplyTo, m.ConversationID, ErrNotFound)
+	}
+	return nil
+}
+
+// insertMessage stores a new message with its attachment, and returns it as read back from the database. It becomes
+// the last message of the conversation, unread by the other members (the receipt of the sender is left alone), and it
+// expires according to the timer of the conversation. Mentions are not recorded in forwarded messages.
+func insertMessage(ctx context.Context, tx *sql.Tx, senderID string, m NewMessage, forwarded bool) (Message, error) {
+	id, err := newID()
+	if err != nil {
+		return Message{}, err
+	}
+
+	var timer string
+	if err := tx.QueryRowContext(ctx, "SELECT message_timer FROM conversations WHERE id = ?", m.ConversationID).Scan(&timer); err != nil {
+		return Message{}, err
+	}
+	createdAt := now()
+	var expiresAt sql.NullInt64
+	if ttl := messageTimers[timer]; ttl > 0 {
+		expiresAt = sql.NullInt64{Int64: createdAt + ttl.Milliseconds(), Valid: true}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, forwarded, created_at, reply_to, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)`,
+		id, m.ConversationID, senderID, m.Content, forwarded, createdAt, m.ReplyTo, expiresAt)
+	if err != nil {
+		return Message{}, err
+	}
+	if m.Attachment != nil {
+		if err := insertAttachment(ctx, tx, id, m.Attachment, createdAt); err != nil {
+			return Message{}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE conversations SET last_message_id = ?, last_activity_at = ? WHERE id = ?",
+		id, createdAt, m.ConversationID)
+	if err != nil {
+		return Message{}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE conversation_members SET unread_count = unread_count + 1 WHERE conversation_id = ? AND user_id != ?`,
+		m.ConversationID, senderID)
+	if err != nil {
+		return Message{}, err
+	}
+	if !forwarded {
+		if err := saveMentions(ctx, tx, m.ConversationID, senderID, id, m.Content); err != nil {
+			return Message{}, err
+		}
+		if err := countMentions(ctx, tx, m.ConversationID, id, createdAt, 1); err != nil {
+			return Message{}, err
+		}
+	}
+
+	message, err := scanMessage(tx.QueryRowContext(ctx, messageSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		return message, err
+	}
+	messages := []Message{message}
+	err = loadMentions(ctx, tx, messages)
+	return messages[0], err
+}
+
+// insertSystemMessage records a change to a conversation, made by actorID, as a system message, and returns it as read
+// back from the database. Unlike insertMessage, it leaves the receipts, the unread counts and the last message of the
+// conversation untouched.
+func insertSystemMessage(ctx context.Context, tx *sql.Tx, conversationID string, actorID string, event SystemEvent) (Message, error) {
+	id, err := newID()
+	if err != nil {
+		return Message{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, kind, sender_id, content, created_at) VALUES (?, ?, ?, ?, '', ?)`,
+		id, conversationID, MessageSystem, actorID, now())
+	if err != nil {
+		return Message{}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO system_messages (message_id, action, target_id, old_value, new_value)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))`,
+		id, event.Action, event.TargetID, event.OldValue, event.NewValue)
+	if err != nil {
+		return Message{}, err
+	}
+	return scanMessage(tx.QueryRowContext(ctx, messageSelect+" WHERE m.id = ?", id))
+}
+
+// insertAttachment stores the attachment of a new message. Its content must have been registered as a blob.
+func insertAttachment(ctx context.Context, tx *sql.Tx, messageID string, a *Attachment, createdAt int64) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO attachments (id, message_id, file_name, mime_type, size, width, height, checksum, created_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?)`,
+		id, messageID, a.FileName, a.MIMEType, a.Size, a.Width, a.Height, a.Checksum, createdAt)
+	return err
+}
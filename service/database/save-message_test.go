@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReply(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	other := startConversation(t, db, alice.ID, carol.ID)
+	question := sendText(t, db, alice.ID, c.ID, strings.Repeat("?", previewLength+1))
+
+	reply, err := db.SaveMessage(ctx, bob.ID, NewMessage{ConversationID: c.ID, Content: "yes", ReplyTo: question.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Quote{ID: question.ID, SenderID: alice.ID, Sender: "alice", Excerpt: strings.Repeat("?", previewLength-1) + "…"}
+	if reply.ReplyTo == nil || *reply.ReplyTo != want {
+		t.Errorf("quote = %+v, want %+v", reply.ReplyTo, want)
+	}
+
+	// The quote follows the replied message
+	if err := db.DeleteMessage(ctx, alice.ID, question.ID); err != nil {
+		t.Fatal(err)
+	}
+	messages, _, err := db.GetMessages(ctx, alice.ID, c.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Quote{ID: question.ID, SenderID: alice.ID, Sender: "alice", Deleted: true}
+	if len(messages) != 2 || messages[1].ID != reply.ID || messages[1].ReplyTo == nil || *messages[1].ReplyTo != want {
+		t.Errorf("messages = %+v, want the reply quoting a deleted message", messages)
+	}
+
+	// Only the messages of the same conversation can be replied to, unless deleted or sent by the system
+	answer := sendText(t, db, carol.ID, other.ID, "hi")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	system, err := db.SetMessageTimer(ctx, alice.ID, group.ID, TimerDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name           string
+		conversationID string
+		replyTo        string
+	}{
+		{"another conversation", c.ID, answer.ID},
+		{"a deleted message", c.ID, question.ID},
+		{"a system message", group.ID, system.ID},
+		{"an unknown message", c.ID, "nonexistent0"},
+	} {
+		_, err := db.SaveMessage(ctx, alice.ID, NewMessage{ConversationID: tt.conversationID, Content: "?", ReplyTo: tt.replyTo})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("reply to %s = %v, want ErrNotFound", tt.name, err)
+		}
+	}
+}
+
+func TestSystemMessages(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendText(t, db, alice.ID, group.ID, "hello")
+
+	if _, err := db.AddUserToGroup(ctx, alice.ID, group.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpdateGroupName(ctx, alice.ID, group.ID, "family"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RegisterBlob(ctx, "photo", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SaveGroupPhoto(ctx, alice.ID, group.ID, "photo", "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.LeaveGroup(ctx, carol.ID, group.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recorded in the history, with the actor and the target, but neither unread nor shown as the last message
+	messages, _, err := db.GetMessages(ctx, bob.ID, group.ID, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SystemEvent{
+		{Action: ActionMemberAdded, TargetID: carol.ID, Target: "carol"},
+		{Action: ActionGroupRenamed, OldValue: "friends", NewValue: "family"},
+		{Action: ActionGroupPhotoChanged},
+		{Action: ActionMemberLeft},
+	}
+	if len(messages) != len(want)+1 {
+		t.Fatalf("got %d messages, want %d", len(messages), len(want)+1)
+	}
+	for i, m := range messages[1:] {
+		wantSender := alice.ID
+		if i == 3 {
+			wantSender = carol.ID
+		}
+		if m.Kind != MessageSystem || m.System == nil || *m.System != want[i] || m.SenderID != wantSender {
+			t.Errorf("message %d = %s by %s, %+v, want %+v by %s", i, m.Kind, m.SenderID, m.System, want[i], wantSender)
+		}
+	}
+	conversation, err := db.GetConversation(ctx, bob.ID, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation.UnreadCount != 1 || conversation.LastMessage != "hello" {
+		t.Errorf("%d unread, last message %q, want 1 unread, hello", conversation.UnreadCount, conversation.LastMessage)
+	}
+
+	// Nothing is recorded for the changes that fail, or don't change anything
+	if _, err := db.UpdateGroupName(ctx, bob.ID, group.ID, "bob's"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("renamed by a plain member = %v, want ErrForbidden", err)
+	}
+	if _, err := db.AddUserToGroup(ctx, alice.ID, group.ID, bob.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("adding a member again = %v, want ErrConflict", err)
+	}
+	if m, err := db.UpdateGroupName(ctx, alice.ID, group.ID, "family"); err != nil || m.ID != "" {
+		t.Errorf("renaming with the same name = %+v, %v, want no message", m, err)
+	}
+	if after, _, err := db.GetMessages(ctx, bob.ID, group.ID, Page{}); err != nil || len(after) != len(messages) {
+		t.Errorf("got %d messages, %v, want %d", len(after), err, len(messages))
+	}
+}
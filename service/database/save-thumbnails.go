@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SaveThumbnails records the thumbnails of an image blob. The thumbnails of a blob depend only on its content: when the
+// same image is uploaded again, the thumbnails already recorded are kept.
+func (db *appdbimpl) SaveThumbnails(ctx context.Context, key string, thumbnails []Thumbnail) error {
+	return db.withTx(ctx, func(tx *sql.Tx) error {
+		for _, t := range thumbnails {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO thumbnails (blob_key, size, thumbnail_key, mime_type, width, height) VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (blob_key, size) DO NOTHING`, key, t.Size, t.Key, t.MIMEType, t.Width, t.Height)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
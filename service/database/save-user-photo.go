@@ -0,0 +1,23 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// SaveUserPhoto replaces the profile photo of the user with the blob photoKey, which must have been registered.
+func (db *appdbimpl) SaveUserPhoto(ctx context.Context, userID string, photoKey string, mimeType string) error {
+	res, err := db.c.ExecContext(ctx, `
+		UPDATE users SET photo_key = ?, photo_mime_type = ?, photo_updated_at = ? WHERE id = ?`,
+		photoKey, mimeType, now(), userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// scheduledSelect selects scheduled messages, to be scanned with scanScheduledMessage.
+const scheduledSelect = `
+	SELECT id, conversation_id, sender_id, content, reply_to, file_name, mime_type, size, width, height, checksum,
+		send_at, created_at, failed_at
+	FROM scheduled_messages`
+
+func scanScheduledMessage(row scanner) (ScheduledMessage, error) {
+	var m ScheduledMessage
+	var replyTo, fileName, mimeType, checksum sql.NullString
+	var size, width, height, failedAt sql.NullInt64
+	var sendAt, createdAt int64
+	err := row.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &replyTo, &fileName, &mimeType, &size, &width, &height,
+		&checksum, &sendAt, &createdAt, &failedAt)
+	m.ReplyTo = replyTo.String
+	if checksum.Valid {
+		m.Attachment = &Attachment{
+			FileName: fileName.String,
+			MIMEType: mimeType.String,
+			Size:     size.Int64,
+			Width:    int(width.Int64),
+			Height:   int(height.Int64),
+			Checksum: checksum.String,
+		}
+	}
+	m.SendAt = fromMillis(sendAt)
+	m.CreatedAt = fromMillis(createdAt)
+	if failedAt.Valid {
+		t := fromMillis(failedAt.Int64)
+		m.FailedAt = &t
+	}
+	return m, err
+}
+
+// ScheduleMessage stores a message to be sent by the user at sendAt, with the same checks as SaveMessage. The content of
+// the attachment, if any, must have been registered as a blob.
+func (db *appdbimpl) ScheduleMessage(ctx context.Context, userID string, m NewMessage, sendAt time.Time) (ScheduledMessage, error) {
+	var scheduled ScheduledMessage
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkMember(ctx, tx, m.ConversationID, userID); err != nil {
+			return err
+		}
+		if err := checkReply(ctx, tx, m); err != nil {
+			return err
+		}
+
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		var a Attachment
+		if m.Attachment != nil {
+			a = *m.Attachment
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO scheduled_messages (id, conversation_id, sender_id, content, reply_to, file_name, mime_type, size,
+				width, height, checksum, send_at, created_at)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0),
+				NULLIF(?, ''), ?, ?)`,
+			id, m.ConversationID, userID, m.Content, m.ReplyTo, a.FileName, a.MIMEType, a.Size, a.Width, a.Height,
+			a.Checksum, sendAt.UnixMilli(), now())
+		if err != nil {
+			return err
+		}
+
+		scheduled, err = scanScheduledMessage(tx.QueryRowContext(ctx, scheduledSelect+" WHERE id = ?", id))
+		return err
+	})
+	return scheduled, err
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// searchIndex creates the full-text index of the messages, indexing the existing ones, as migration 0012 does for the
+// databases migrated with FTS5. The index keeps its own copy of the content, needed for the snippets, and it's kept in
+// sync with the messages by the triggers below: deleted messages are removed, edited ones indexed again.
+const searchIndex = `
+CREATE VIRTUAL TABLE message_search USING fts5(content, tokenize = 'unicode61 remove_diacritics 2');
+
+DELETE FROM message_search_keys;
+
+INSERT INTO message_search_keys (message_id)
+SELECT id FROM messages WHERE content != '' AND deleted_at IS NULL;
+
+INSERT INTO message_search (rowid, content)
+SELECT k.id, m.content FROM message_search_keys k JOIN messages m ON m.id = k.message_id;
+
+CREATE TRIGGER messages_search_insert AFTER INSERT ON messages WHEN NEW.content != '' AND NEW.deleted_at IS NULL
+BEGIN
+	INSERT INTO message_search_keys (message_id) VALUES (NEW.id);
+	INSERT INTO message_search (rowid, content)
+	SELECT id, NEW.content FROM message_search_keys WHERE message_id = NEW.id;
+END;
+
+CREATE TRIGGER messages_search_update AFTER UPDATE OF content, deleted_at ON messages
+BEGIN
+	DELETE FROM message_search WHERE rowid = (SELECT id FROM message_search_keys WHERE message_id = OLD.id);
+	DELETE FROM message_search_keys WHERE message_id = OLD.id;
+	INSERT INTO message_search_keys (message_id)
+	SELECT NEW.id WHERE NEW.content != '' AND NEW.deleted_at IS NULL;
+	INSERT INTO message_search (rowid, content)
+	SELECT id, NEW.content FROM message_search_keys WHERE message_id = NEW.id;
+END;
+
+CREATE TRIGGER messages_search_delete AFTER DELETE ON messages
+BEGIN
+	DELETE FROM message_search WHERE rowid = (SELECT id FROM message_search_keys WHERE message_id = OLD.id);
+	DELETE FROM message_search_keys WHERE message_id = OLD.id;
+END;`
+
+// hasFTS5 reports whether SQLite has the FTS5 extension.
+func hasFTS5(db *sql.DB) (bool, error) {
+	var fts5 bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return false, fmt.Errorf("checking SQLite features: %w", err)
+	}
+	return fts5, nil
+}
+
+// setupSearch prepares the full-text search of the messages, once the schema is up to date, and returns whether it's
+// available. The index is created the first time the database is opened by an executable whose SQLite has FTS5, if
+// migration 0012 was skipped; ErrFTS5Unavailable is returned if the database has it but SQLite doesn't, as the triggers
+// keeping it in sync would fail.
+func setupSearch(db *sql.DB, fts5 bool) (bool, error) {
+	var indexed bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'message_search')").Scan(&indexed)
+	if err != nil {
+		return false, fmt.Errorf("checking search index: %w", err)
+	}
+
+	switch {
+	case indexed && !fts5:
+		return false, ErrFTS5Unavailable
+	case indexed || !fts5:
+		return fts5, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.Exec(searchIndex); err != nil {
+		return false, fmt.Errorf("creating search index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("creating search index: %w", err)
+	}
+	return true, nil
+}
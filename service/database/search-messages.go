@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search selects the messages to return from SearchMessages. Text is matched against the words of the messages (all
+// of them must appear, the last one may be the beginning of a word), or against their content without the full-text
+// index (each word must appear in it); the other fields, when set, restrict the search to a conversation, to a sender
+// and to the messages sent in a time range. Before and After are exclusive.
+type Search struct {
+	Text           string
+	ConversationID string
+	SenderID       string
+	Before         time.Time
+	After          time.Time
+	Cursor         *SearchCursor
+	Limit          int
+}
+
+// SearchCursor is a position in the results of a search, which are sorted by rank, with the message identifier to
+// break ties. Results strictly past the cursor are returned.
+type SearchCursor struct {
+	Rank float64
+	ID   string
+}
+
+// Markers wrapped around the matches by snippet(). They are control characters, so they can't be confused with the
+// HTML they are replaced with once the snippet is escaped.
+const (
+	snippetMatchStart = "\x02"
+	snippetMatchEnd   = "\x03"
+)
+
+// snippetTokens is the maximum number of words in a snippet.
+const snippetTokens = 16
+
+func (db *appdbimpl) SearchMessages(ctx context.Context, userID string, search Search) ([]SearchResult, bool, error) {
+	if search.ConversationID != "" {
+		if err := checkMember(ctx, db.c, search.ConversationID, userID); err != nil {
+			return nil, false, err
+		}
+	}
+	words := strings.Fields(search.Text)
+	if len(words) == 0 {
+		return []SearchResult{}, false, nil
+	}
+
+	// The rank is the BM25 score of the message with the full-text index, lower for better matches; without it, the
+	// messages are sorted by time, newest first
+	var results, cond string
+	args := []interface{}{userID}
+	if db.fts {
+		results = `
+			SELECT k.message_id AS id, s.rank AS rank,
+				snippet(message_search, 0, '` + snippetMatchStart + `', '` + snippetMatchEnd + `', '…', ` + strconv.Itoa(snippetTokens) + `) AS snippet
+			FROM message_search s
+			JOIN message_search_keys k ON k.id = s.rowid
+			JOIN messages m ON m.id = k.message_id
+			JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = ?
+			WHERE message_search MATCH ?`
+		args = append(args, matchQuery(words))
+	} else {
+		results = `
+			SELECT m.id AS id, -m.created_at AS rank, m.content AS snippet
+			FROM messages m
+			JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = ?
+			WHERE m.content != '' AND m.deleted_at IS NULL`
+		for _, word := range words {
+			cond += ` AND m.content LIKE ? ESCAPE '\'`
+			args = append(args, "%"+likeEscaper.Replace(word)+"%")
+		}
+	}
+	cond += notExpired
+	args = append(args, now())
+	if search.ConversationID != "" {
+		cond += " AND m.conversation_id = ?"
+		args = append(args, search.ConversationID)
+	}
+	if search.SenderID != "" {
+		cond += " AND m.sender_id = ?"
+		args = append(args, search.SenderID)
+	}
+	if !search.Before.IsZero() {
+		cond += " AND m.created_at < ?"
+		args = append(args, search.Before.UnixMilli())
+	}
+	if !search.After.IsZero() {
+		cond += " AND m.created_at > ?"
+		args = append(args, search.After.UnixMilli())
+	}
+	var outer string
+	if search.Cursor != nil {
+		outer = " WHERE (rank, id) > (?, ?)"
+		args = append(args, search.Cursor.Rank, search.Cursor.ID)
+	}
+	page := Page{Limit: search.Limit}
+
+	rows, err := db.c.QueryContext(ctx, `
+		SELECT id, rank, snippet FROM (`+results+cond+`
+		)`+outer+`
+		ORDER BY rank, id
+		LIMIT `+strconv.Itoa(page.size()+1), args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	found := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Message.ID, &r.Rank, &r.Snippet); err != nil {
+			return nil, false, err
+		}
+		if !db.fts {
+			r.Snippet = likeSnippet(r.Snippet, words)
+		}
+		r.Snippet = highlight(r.Snippet)
+		found = append(found, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	_ = rows.Close()
+
+	more := page.hasMore(len(found))
+	if more {
+		found = found[:len(found)-1]
+	}
+	if err := loadSearchResults(ctx, db.c, userID, found); err != nil {
+		return nil, false, err
+	}
+	return found, more, nil
+}
+
+// loadSearchResults replaces the messages of the results, which only have their identifier set, with the full
+// messages, including their reactions, stars (as seen by userID) and statuses.
+func loadSearchResults(ctx context.Context, q querier, userID string, results []SearchResult) error {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Message.ID)
+	}
+	messages, err := loadMessages(ctx, q, userID, ids)
+	if err != nil {
+		return err
+	}
+	for i, r := range results {
+		if m, ok := messages[r.Message.ID]; ok {
+			results[i].Message = m
+		}
+	}
+	return nil
+}
+
+// matchQuery converts the words of a search into an FTS5 query. Each word is quoted, so that the FTS5 syntax can't be
+// used (or misused) by clients, and the last one matches as a prefix, as the user may still be typing it.
+func matchQuery(words []string) string {
+	var terms []string
+	for _, word := range words {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// likeSnippet makes the snippet of a message found without the full-text index, like snippet() does: the occurrences
+// of the words in content are wrapped in the match markers, ignoring ASCII case (as LIKE does), and content longer than
+// snippetTokens words is cut around the first one.
+func likeSnippet(content string, words []string) string {
+	// Find the matches, merging the overlapping ones
+	folded := asciiLower(content)
+	var matches [][2]int
+	for _, word := range words {
+		word = asciiLower(word)
+		for i := 0; ; {
+			j := strings.Index(folded[i:], word)
+			if j < 0 {
+				break
+			}
+			matches = append(matches, [2]int{i + j, i + j + len(word)})
+			i += j + len(word)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+	var merged [][2]int
+	for _, m := range matches {
+		if n := len(merged); n > 0 && m[0] <= merged[n-1][1] {
+			if m[1] > merged[n-1][1] {
+				merged[n-1][1] = m[1]
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+
+	// Cut the content to snippetTokens words, starting a couple of words before the first match
+	start, end := 0, len(content)
+	var prefix, suffix string
+	if spans := wordSpans(content); len(spans) > snippetTokens {
+		first := 0
+		if len(merged) > 0 {
+			for first < len(spans)-1 && spans[first][1] <= merged[0][0] {
+				first++
+			}
+		}
+		from := first - 2
+		if from > len(spans)-snippetTokens {
+			from = len(spans) - snippetTokens
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := from + snippetTokens
+		start, end = spans[from][0], spans[to-1][1]
+		if from > 0 {
+			prefix = "…"
+		}
+		if to < len(spans) {
+			suffix = "…"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	pos := start
+	for _, m := range merged {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+		if m[0] < start {
+			m[0] = start
+		}
+		if m[1] > end {
+			m[1] = end
+		}
+		b.WriteString(content[pos:m[0]])
+		b.WriteString(snippetMatchStart + content[m[0]:m[1]] + snippetMatchEnd)
+		pos = m[1]
+	}
+	b.WriteString(content[pos:end])
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// wordSpans returns the byte offsets of the start and end of each word of s, words being separated by white space.
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+// asciiLower returns s with the ASCII letters in lower case. Unlike strings.ToLower, it keeps the offsets of s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// highlight turns a snippet into HTML text, with the matches wrapped in <mark> elements.
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, snippetMatchStart, "<mark>")
+	return strings.ReplaceAll(snippet, snippetMatchEnd, "</mark>")
+}
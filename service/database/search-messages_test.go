@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMatchQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"cafe", `"cafe"*`},
+		{"meet at  the cafe", `"meet" "at" "the" "cafe"*`},
+		// The FTS5 syntax is quoted: operators, columns, prefixes and quotes are plain text
+		{"a OR b", `"a" "OR" "b"*`},
+		{`say "hi`, `"say" """hi"*`},
+		{"content:x NEAR(y z) w*", `"content:x" "NEAR(y" "z)" "w*"*`},
+	}
+	for _, tt := range tests {
+		if got := matchQuery(strings.Fields(tt.text)); got != tt.want {
+			t.Errorf("matchQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLikeSnippet(t *testing.T) {
+	mark := func(s string) string {
+		return strings.NewReplacer("[", snippetMatchStart, "]", snippetMatchEnd).Replace(s)
+	}
+	long := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen"
+	tests := []struct {
+		content string
+		words   []string
+		want    string
+	}{
+		{"Meet at the Cafe", []string{"cafe"}, "Meet at the [Cafe]"},
+		{"cafe, CAFE and café", []string{"caf"}, "[caf]e, [CAF]E and [caf]é"},
+		{"100% sure", []string{"100%"}, "[100%] sure"},
+		// Overlapping matches are merged
+		{"banana", []string{"ana", "nan"}, "b[anan]a"},
+		// Long content is cut around the first match
+		{long + " match end", []string{"match"}, "…three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen [match] end"},
+		{"match " + long, []string{"match"}, "[match] one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen…"},
+		{long + " " + long, []string{"five"}, "…three four [five] six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen one two…"},
+	}
+	for _, tt := range tests {
+		if got, want := likeSnippet(tt.content, tt.words), mark(tt.want); got != want {
+			t.Errorf("likeSnippet(%q, %q) = %q, want %q", tt.content, tt.words, got, want)
+		}
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+	c := startConversation(t, db, alice.ID, bob.ID)
+	cafe := sendText(t, db, alice.ID, c.ID, "Meet at the Cafe tomorrow")
+	percent := sendText(t, db, bob.ID, c.ID, "100% sure")
+	sendText(t, db, bob.ID, c.ID, "nothing to see")
+	deleted := sendText(t, db, alice.ID, c.ID, "another cafe")
+	if err := db.DeleteMessage(ctx, alice.ID, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(userID string, text string) []string {
+		t.Helper()
+		results, _, err := db.SearchMessages(ctx, userID, Search{Text: text, Limit: 10})
+		if err != nil {
+			t.Fatalf("searching %q: %v", text, err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.Message.ID)
+		}
+		return ids
+	}
+
+	if got := search(bob.ID, "cafe"); len(got) != 1 || got[0] != cafe.ID {
+		t.Errorf("search cafe = %v, want [%s]", got, cafe.ID)
+	}
+	if got := search(bob.ID, "meet caf"); len(got) != 1 || got[0] != cafe.ID {
+		t.Errorf("search meet caf = %v, want [%s]", got, cafe.ID)
+	}
+	if got := search(alice.ID, "100%"); len(got) != 1 || got[0] != percent.ID {
+		t.Errorf("search 100%% = %v, want [%s]", got, percent.ID)
+	}
+	// Neither the FTS5 syntax nor the LIKE wildcards match everything
+	for _, text := range []string{"_", "*", `"`, "NEAR(a b)", "cafe OR nothing"} {
+		if got := search(alice.ID, text); len(got) != 0 {
+			t.Errorf("search %q = %v, want nothing", text, got)
+		}
+	}
+	// Only the conversations of the user are searched
+	if got := search(carol.ID, "cafe"); len(got) != 0 {
+		t.Errorf("search by non-member = %v, want nothing", got)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns, with `\` as escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers returns the users, other than userID, whose name starts with prefix (ignoring case), sorted by name.
+// Only names greater than after are returned, if not empty. more reports whether there are other users past the page.
+func (db *appdbimpl) SearchUsers(ctx context.Context, userID string, prefix string, after string, limit int) (users []DirectoryUser, more bool, err error) {
+	// Names are case-insensitive (COLLATE NOCASE), and so are the comparisons below: LIKE can then use their index
+	query := `
+		SELECT id, name, photo_key IS NOT NULL
+		FROM users
+		WHERE id != ? AND name LIKE ? ESCAPE '\'`
+	args := []interface{}{userID, likeEscaper.Replace(prefix) + "%"}
+	if after != "" {
+		query += " AND name > ?"
+		args = append(args, after)
+	}
+	page := Page{Limit: limit}
+	rows, err := db.c.QueryContext(ctx, query+" ORDER BY name LIMIT "+strconv.Itoa(page.size()+1), args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	users = []DirectoryUser{}
+	for rows.Next() {
+		var u DirectoryUser
+		if err := rows.Scan(&u.ID, &u.Name, &u.HasPhoto); err != nil {
+			return nil, false, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if more = page.hasMore(len(users)); more {
+		users = users[:len(users)-1]
+	}
+	return users, more, nil
+}
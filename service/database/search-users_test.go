@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// userNames returns the names of the users, comma-separated.
+func userNames(users []DirectoryUser) string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	me := createUser(t, db, "john")
+	for _, name := range []string{"Joanna", "jo_hn", "joe", "JoJo", "jordan", "mark", "jo%"} {
+		createUser(t, db, name)
+	}
+
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"jo", "jo%,jo_hn,Joanna,joe,JoJo,jordan"},
+		{"JO", "jo%,jo_hn,Joanna,joe,JoJo,jordan"},
+		{"jo_", "jo_hn"}, // not a wildcard
+		{"jo%", "jo%"},   // neither is this
+		{"john", ""},     // the user is left out
+		{"markus", ""},
+	}
+	for _, tt := range tests {
+		users, more, err := db.SearchUsers(ctx, me.ID, tt.prefix, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := userNames(users); got != tt.want || more {
+			t.Errorf("SearchUsers(%q) = %s (more: %v), want %s", tt.prefix, got, more, tt.want)
+		}
+	}
+
+	// Pages of two users, after the name of the last one
+	var pages []string
+	after := ""
+	for more := true; more; {
+		var users []DirectoryUser
+		var err error
+		users, more, err = db.SearchUsers(ctx, me.ID, "jo", after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, userNames(users))
+		after = users[len(users)-1].Name
+	}
+	if got, want := strings.Join(pages, " | "), "jo%,jo_hn | Joanna,joe | JoJo,jordan"; got != want {
+		t.Errorf("pages = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// scheduledBatch is the maximum number of scheduled messages handled by a single call of SendScheduledMessages.
+const scheduledBatch = 100
+
+// SendScheduledMessages sends the scheduled messages due at the given time, the first due first, and returns them as
+// sent. A message is sent as if its sender sent it now, so that the messages due while the server was down are sent
+// late, but still in order. Each is sent in its own transaction, so that a concurrent cancellation either takes effect
+// or fails with ErrNotFound.
+//
+// A reply to a message that can't be replied to anymore (deleted or expired in the meantime) is sent as a plain
+// message; the messages whose sender is no longer a member of the conversation are discarded, and counted in
+// discarded. A message that can't be sent is marked as failed, and the others are sent all the same: the errors are
+// returned together.
+func (db *appdbimpl) SendScheduledMessages(ctx context.Context, at time.Time) (sent []Message, discarded int, err error) {
+	rows, err := db.c.QueryContext(ctx, scheduledSelect+`
+		WHERE send_at <= ? AND failed_at IS NULL ORDER BY send_at, created_at, id LIMIT ?`, at.UnixMilli(), scheduledBatch)
+	if err != nil {
+		return nil, 0, err
+	}
+	var due []ScheduledMessage
+	for rows.Next() {
+		m, err := scanScheduledMessage(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, 0, err
+		}
+		due = append(due, m)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, 0, err
+	}
+	_ = rows.Close()
+
+	var errs []error
+	for _, scheduled := range due {
+		message, discard, err := db.sendScheduledMessage(ctx, scheduled)
+		if err != nil {
+			_, markErr := db.c.ExecContext(ctx, "UPDATE scheduled_messages SET failed_at = ? WHERE id = ?", now(), scheduled.ID)
+			errs = append(errs, fmt.Errorf("scheduled message %s: %w", scheduled.ID, errors.Join(err, markErr)))
+		} else if discard {
+			discarded++
+		} else if message.ID != "" {
+			sent = append(sent, message)
+		}
+	}
+	return sent, discarded, errors.Join(errs...)
+}
+
+// sendScheduledMessage sends a scheduled message, or discards it if its sender is no longer a member of the
+// conversation. The message returned is empty if the scheduled message has been cancelled in the meantime.
+func (db *appdbimpl) sendScheduledMessage(ctx context.Context, scheduled ScheduledMessage) (message Message, discard bool, err error) {
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		// The message may have been cancelled in the meantime
+		res, err := tx.ExecContext(ctx, "DELETE FROM scheduled_messages WHERE id = ?", scheduled.ID)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		if err := checkMember(ctx, tx, scheduled.ConversationID, scheduled.SenderID); errors.Is(err, ErrForbidden) {
+			discard = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		m := NewMessage{
+			ConversationID: scheduled.ConversationID,
+			Content:        scheduled.Content,
+			ReplyTo:        scheduled.ReplyTo,
+			Attachment:     scheduled.Attachment,
+		}
+		if err := checkReply(ctx, tx, m); errors.Is(err, ErrNotFound) {
+			m.ReplyTo = ""
+		} else if err != nil {
+			return err
+		}
+
+		message, err = insertMessage(ctx, tx, scheduled.SenderID, m, false)
+		return err
+	})
+	if err != nil {
+		return Message{}, false, err
+	}
+	return message, discard, nil
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schedule schedules a text message, to be sent at sendAt.
+func schedule(t *testing.T, db AppDatabase, userID string, m NewMessage, sendAt time.Time) ScheduledMessage {
+	t.Helper()
+	scheduled, err := db.ScheduleMessage(context.Background(), userID, m, sendAt)
+	if err != nil {
+		t.Fatalf("scheduling %q: %v", m.Content, err)
+	}
+	return scheduled
+}
+
+// scheduledContents returns the contents of the messages scheduled by the user, comma-separated.
+func scheduledContents(t *testing.T, db AppDatabase, userID string) string {
+	t.Helper()
+	messages, err := db.GetScheduledMessages(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("getting scheduled messages: %v", err)
+	}
+	contents := make([]string, len(messages))
+	for i, m := range messages {
+		contents[i] = m.Content
+	}
+	return strings.Join(contents, ",")
+}
+
+func TestScheduleMessage(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob, eve := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "eve")
+	c := startConversation(t, db, alice.ID, bob.ID)
+
+	later := schedule(t, db, alice.ID, NewMessage{ConversationID: c.ID, Content: "later"}, start.Add(2*time.Hour))
+	soon := schedule(t, db, alice.ID, NewMessage{ConversationID: c.ID, Content: "soon"}, start.Add(time.Hour))
+	if !soon.SendAt.Equal(start.Add(time.Hour)) || !soon.CreatedAt.Equal(start) || soon.FailedAt != nil {
+		t.Errorf("scheduled message = %+v", soon)
+	}
+
+	// Only the sender sees them, the first to be sent first
+	if got := scheduledContents(t, db, alice.ID); got != "soon,later" {
+		t.Errorf("scheduled messages = %s, want soon,later", got)
+	}
+	if got := scheduledContents(t, db, bob.ID); got != "" {
+		t.Errorf("scheduled messages of the recipient = %s, want none", got)
+	}
+
+	// The same checks as for sending right away
+	_, err := db.ScheduleMessage(ctx, eve.ID, NewMessage{ConversationID: c.ID, Content: "hi"}, start.Add(time.Hour))
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("scheduling by a non-member = %v, want ErrForbidden", err)
+	}
+	_, err = db.ScheduleMessage(ctx, alice.ID, NewMessage{ConversationID: c.ID, Content: "hi", ReplyTo: "nonexistent0"},
+		start.Add(time.Hour))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("scheduling a reply to an unknown message = %v, want ErrNotFound", err)
+	}
+
+	// Only the sender can cancel them, once
+	if err := db.DeleteScheduledMessage(ctx, bob.ID, later.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancelling by the recipient = %v, want ErrNotFound", err)
+	}
+	if err := db.DeleteScheduledMessage(ctx, alice.ID, later.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteScheduledMessage(ctx, alice.ID, later.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancelling again = %v, want ErrNotFound", err)
+	}
+	if got := scheduledContents(t, db, alice.ID); got != "soon" {
+		t.Errorf("scheduled messages after cancelling = %s, want soon", got)
+	}
+}
+
+func TestSendScheduledMessages(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob, carol := createUser(t, db, "alice"), createUser(t, db, "bob"), createUser(t, db, "carol")
+	group, err := db.CreateGroup(ctx, alice.ID, "friends", []string{bob.ID, carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	question := sendText(t, db, bob.ID, group.ID, "anyone?")
+
+	schedule(t, db, alice.ID, NewMessage{ConversationID: group.ID, Content: "second"}, start.Add(2*time.Hour))
+	schedule(t, db, alice.ID, NewMessage{ConversationID: group.ID, Content: "first"}, start.Add(time.Hour))
+	schedule(t, db, alice.ID, NewMessage{ConversationID: group.ID, Content: "not yet"}, start.Add(3*time.Hour))
+	schedule(t, db, alice.ID, NewMessage{ConversationID: group.ID, Content: "me!", ReplyTo: question.ID},
+		start.Add(time.Hour+time.Minute))
+	schedule(t, db, carol.ID, NewMessage{ConversationID: group.ID, Content: "bye"}, start.Add(time.Hour))
+
+	// The replied message is deleted, and carol leaves, before the messages are due
+	if err := db.DeleteMessage(ctx, bob.ID, question.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.LeaveGroup(ctx, carol.ID, group.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sent late, in order, as if sent now; the message of a former member is discarded
+	at := start.Add(2 * time.Hour)
+	setTime(t, at.Add(time.Minute))
+	sent, discarded, err := db.SendScheduledMessages(ctx, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discarded != 1 {
+		t.Errorf("discarded %d messages, want 1", discarded)
+	}
+	var contents []string
+	for _, m := range sent {
+		contents = append(contents, m.Content)
+		if m.SenderID != alice.ID || !m.Timestamp.Equal(at.Add(time.Minute)) {
+			t.Errorf("message %q sent by %s at %v, want alice at %v", m.Content, m.SenderID, m.Timestamp, at.Add(time.Minute))
+		}
+	}
+	if got := strings.Join(contents, ","); got != "first,me!,second" {
+		t.Errorf("sent %s, want first,me!,second", got)
+	}
+	if len(sent) == 3 && sent[1].ReplyTo != nil {
+		t.Errorf("reply to a deleted message sent with the quote %+v, want a plain message", sent[1].ReplyTo)
+	}
+	if got := scheduledContents(t, db, alice.ID); got != "not yet" {
+		t.Errorf("scheduled messages left = %s, want not yet", got)
+	}
+	if got := scheduledContents(t, db, carol.ID); got != "" {
+		t.Errorf("scheduled messages of the former member = %s, want none", got)
+	}
+	if sent, discarded, err := db.SendScheduledMessages(ctx, at); err != nil || len(sent) != 0 || discarded != 0 {
+		t.Errorf("sending again = %d sent, %d discarded, %v, want nothing", len(sent), discarded, err)
+	}
+}
+
+func TestSendScheduledMessagesFailure(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setTime(t, start)
+	alice, bob := createUser(t, db, "alice"), createUser(t, db, "bob")
+	c := startConversation(t, db, alice.ID, bob.ID)
+
+	// A message that can't be stored, due before another one
+	_, err := db.c.Exec(`
+		CREATE TRIGGER broken_messages BEFORE INSERT ON messages WHEN NEW.content = 'broken'
+		BEGIN
+			SELECT RAISE(ABORT, 'broken message');
+		END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := schedule(t, db, alice.ID, NewMessage{ConversationID: c.ID, Content: "broken"}, start.Add(time.Hour))
+	schedule(t, db, alice.ID, NewMessage{ConversationID: c.ID, Content: "fine"}, start.Add(2*time.Hour))
+
+	// The failure doesn't hold back the next message
+	at := start.Add(2 * time.Hour)
+	setTime(t, at)
+	sent, _, err := db.SendScheduledMessages(ctx, at)
+	if err == nil || !strings.Contains(err.Error(), broken.ID) {
+		t.Errorf("SendScheduledMessages = %v, want the error of %s", err, broken.ID)
+	}
+	if len(sent) != 1 || sent[0].Content != "fine" {
+		t.Errorf("sent %+v, want fine", sent)
+	}
+
+	// The failed message is kept for its sender, and not tried again
+	messages, err := db.GetScheduledMessages(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].ID != broken.ID || messages[0].FailedAt == nil || !messages[0].FailedAt.Equal(at) {
+		t.Errorf("scheduled messages = %+v, want %s failed at %v", messages, broken.ID, at)
+	}
+	if sent, _, err := db.SendScheduledMessages(ctx, at.Add(time.Hour)); err != nil || len(sent) != 0 {
+		t.Errorf("sending again = %d sent, %v, want nothing", len(sent), err)
+	}
+	if err := db.DeleteScheduledMessage(ctx, alice.ID, broken.ID); err != nil {
+		t.Errorf("cancelling the failed message: %v", err)
+	}
+}
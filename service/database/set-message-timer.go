@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SetMessageTimer sets the timer of disappearing messages of a conversation the user is a member of (an admin of, for
+// groups), and returns the system message recording it. The timer applies to the messages sent from now on. Setting
+// the current timer does nothing: the message is then empty.
+func (db *appdbimpl) SetMessageTimer(ctx context.Context, userID string, conversationID string, timer string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkMember(ctx, tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		var isGroup bool
+		var oldTimer string
+		err := tx.QueryRowContext(ctx, "SELECT is_group, message_timer FROM conversations WHERE id = ?", conversationID).
+			Scan(&isGroup, &oldTimer)
+		if err != nil {
+			return err
+		} else if oldTimer == timer {
+			return nil
+		}
+		if isGroup {
+			if err := checkGroupAdmin(ctx, tx, conversationID, userID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE conversations SET message_timer = ? WHERE id = ?", timer, conversationID); err != nil {
+			return err
+		}
+		message, err = insertSystemMessage(ctx, tx, conversationID, userID, SystemEvent{
+			Action:   ActionTimerChanged,
+			OldValue: oldTimer,
+			NewValue: timer,
+		})
+		return err
+	})
+	return message, err
+}
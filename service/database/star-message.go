@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// StarMessage stars a message of a conversation the user is a member of, for the user only. Starring a message again
+// does nothing. Deleted messages and system messages can't be starred.
+func (db *appdbimpl) StarMessage(ctx context.Context, userID string, messageID string) error {
+	return db.withTx(ctx, func(tx *sql.Tx) error {
+		message, err := getVisibleMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if message.Deleted {
+			return fmt.Errorf("message %s: %w", messageID, ErrNotFound)
+		} else if message.Kind == MessageSystem {
+			return fmt.Errorf("message %s is a system message: %w", messageID, ErrConflict)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO starred_messages (user_id, message_id, starred_at) VALUES (?, ?, ?)
+			ON CONFLICT (user_id, message_id) DO NOTHING`, userID, messageID, now())
+		return err
+	})
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// UnpinMessage unpins a message of a conversation the user is a member of (an admin of, for groups), and returns it.
+// ErrNotFound is returned if the message is not pinned.
+func (db *appdbimpl) UnpinMessage(ctx context.Context, userID string, messageID string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		message, err = getPinnableMessage(ctx, tx, userID, messageID)
+		if err != nil {
+			return err
+		} else if !message.Pinned {
+			return fmt.Errorf("pinned message %s: %w", messageID, ErrNotFound)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pinned_messages WHERE message_id = ?", messageID); err != nil {
+			return err
+		}
+		message.Pinned = false
+		return nil
+	})
+	return message, err
+}
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnstarMessage removes the star of the user from a message. ErrNotFound is returned if the user didn't star it.
+func (db *appdbimpl) UnstarMessage(ctx context.Context, userID string, messageID string) error {
+	res, err := db.c.ExecContext(ctx, "DELETE FROM starred_messages WHERE user_id = ? AND message_id = ?", userID, messageID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("starred message %s: %w", messageID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UpdateGroupName renames a group the user is an admin of, and returns the system message recording it. Renaming a
+// group with its current name does nothing: the message is then empty.
+func (db *appdbimpl) UpdateGroupName(ctx context.Context, userID string, groupID string, name string) (Message, error) {
+	var message Message
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGroupAdmin(ctx, tx, groupID, userID); err != nil {
+			return err
+		}
+
+		var oldName string
+		if err := tx.QueryRowContext(ctx, "SELECT name FROM groups WHERE id = ?", groupID).Scan(&oldName); err != nil {
+			return err
+		} else if oldName == name {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE groups SET name = ? WHERE id = ?", name, groupID); err != nil {
+			return err
+		}
+		var err error
+		message, err = insertSystemMessage(ctx, tx, groupID, userID, SystemEvent{
+			Action:   ActionGroupRenamed,
+			OldValue: oldName,
+			NewValue: name,
+		})
+		return err
+	})
+	return message, err
+}
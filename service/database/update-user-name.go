@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateUserName renames the user. Names are unique (case-insensitive): ErrConflict is returned if another user
+// already has the new name.
+func (db *appdbimpl) UpdateUserName(ctx context.Context, userID string, name string) error {
+	res, err := db.c.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", name, userID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("name %q already taken: %w", name, ErrConflict)
+	} else if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("user %s: %w", userID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+// gifFrames counts the frames of a GIF file without decoding them, walking the blocks of the file. See
+// https://www.w3.org/Graphics/GIF/spec-gif89a.txt.
+func gifFrames(data []byte) (int, error) {
+	const (
+		headerLength     = 6 + 7
+		extensionBlock   = 0x21
+		imageDescriptor  = 0x2c
+		trailer          = 0x3b
+		descriptorLength = 10
+		colorTableFlag   = 0x80
+	)
+	truncated := fmt.Errorf("%w: truncated GIF", ErrInvalid)
+
+	// skipSubBlocks returns the position after a sequence of data sub-blocks, ended by an empty one
+	skipSubBlocks := func(pos int) (int, error) {
+		for {
+			if pos >= len(data) {
+				return 0, truncated
+			}
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return pos, nil
+			}
+			pos += size
+		}
+	}
+	colorTableLength := func(flags byte) int {
+		if flags&colorTableFlag == 0 {
+			return 0
+		}
+		return 3 << (flags&7 + 1)
+	}
+
+	if len(data) < headerLength {
+		return 0, truncated
+	}
+	pos := headerLength + colorTableLength(data[10])
+	frames := 0
+	for {
+		if pos >= len(data) {
+			return 0, truncated
+		}
+		var err error
+		switch data[pos] {
+		case trailer:
+			return frames, nil
+
+		case extensionBlock:
+			// Introducer, label, sub-blocks
+			pos, err = skipSubBlocks(pos + 2)
+
+		case imageDescriptor:
+			if pos+descriptorLength > len(data) {
+				return 0, truncated
+			}
+			frames++
+			// Descriptor, local color table, LZW minimum code size, sub-blocks
+			pos += descriptorLength + colorTableLength(data[pos+9]) + 1
+			pos, err = skipSubBlocks(pos)
+
+		default:
+			return 0, fmt.Errorf("%w: unknown GIF block 0x%02x", ErrInvalid, data[pos])
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// firstFrame returns the first frame of an animation, drawn on the whole canvas (frames may cover a part of it only).
+func firstFrame(g *gif.GIF) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	frame := g.Image[0]
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas
+}
@@ -0,0 +1,246 @@
+/*
+Package imaging prepares the images uploaded by users (photos and image attachments) to be stored and served.
+
+Process checks that the content is a well-formed image of a supported format and of reasonable dimensions (guarding
+against decompression bombs: small files that decode to huge images), removes the metadata that may leak private
+information (EXIF, including GPS coordinates, XMP, comments), applies the EXIF orientation to the pixels, and generates
+thumbnails in the fixed sizes of ThumbnailSizes.
+
+JPEG, PNG and GIF are fully supported. WebP images can't be decoded with the standard library: their metadata is
+removed and their dimensions are checked, but they have no thumbnails.
+*/
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+var (
+	// ErrUnsupported is returned when the content is not an image of a supported format.
+	ErrUnsupported = errors.New("unsupported image format")
+
+	// ErrInvalid is returned when the content looks like a supported image, but it can't be decoded.
+	ErrInvalid = errors.New("invalid image")
+
+	// ErrTooLarge is returned when the image (or the sum of the frames of an animated image) exceeds MaxPixels.
+	ErrTooLarge = errors.New("image dimensions too large")
+)
+
+// MaxPixels is the maximum number of pixels of an image. It bounds the memory needed to decode images to about
+// 4 bytes per pixel.
+const MaxPixels = 40 * 1000 * 1000
+
+// ThumbnailSizes are the sizes of the thumbnails, in pixels of the longest side.
+var ThumbnailSizes = []int{64, 256, 1024}
+
+const (
+	// jpegQuality is the quality of re-encoded JPEG images (rotated by the orientation).
+	jpegQuality = 90
+
+	// thumbnailQuality is the quality of JPEG thumbnails.
+	thumbnailQuality = 80
+)
+
+// Image is an image ready to be stored: Data is the content without metadata and with the orientation applied.
+type Image struct {
+	Data       []byte
+	MIMEType   string
+	Width      int
+	Height     int
+	Thumbnails []Thumbnail
+}
+
+// Thumbnail is a reduced copy of an image. Size is the entry of ThumbnailSizes it was generated for: the longest side
+// of the thumbnail. Thumbnails are generated only for sizes smaller than the image.
+type Thumbnail struct {
+	Size     int
+	Data     []byte
+	MIMEType string
+	Width    int
+	Height   int
+}
+
+// Process validates an image and prepares it to be stored, see the package documentation. The format is detected from
+// the content.
+func Process(data []byte) (*Image, error) {
+	var img *Image
+	var decoded image.Image
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		img, decoded, err = processJPEG(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		img, decoded, err = processPNG(data)
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		img, decoded, err = processGIF(data)
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		img, err = processWebP(data)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded != nil {
+		for _, size := range ThumbnailSizes {
+			if img.Width <= size && img.Height <= size {
+				continue
+			}
+			thumbnail, err := makeThumbnail(decoded, size)
+			if err != nil {
+				return nil, err
+			}
+			img.Thumbnails = append(img.Thumbnails, thumbnail)
+		}
+	}
+	return img, nil
+}
+
+// checkDimensions returns ErrTooLarge if an image of the given dimensions (times the number of frames) is over
+// MaxPixels.
+func checkDimensions(width int, height int, frames int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%w: empty image", ErrInvalid)
+	}
+	if int64(width)*int64(height)*int64(frames) > MaxPixels {
+		return fmt.Errorf("%w: %dx%d pixels, %d frames", ErrTooLarge, width, height, frames)
+	}
+	return nil
+}
+
+func processJPEG(data []byte) (*Image, image.Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, 1); err != nil {
+		return nil, nil, err
+	}
+
+	orientation := jpegOrientation(data)
+	stripped, err := stripJPEG(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	if orientation > 1 {
+		// The pixels must be rotated: the image is encoded again, losing its color profile
+		decoded = orient(decoded, orientation)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, nil, err
+		}
+		stripped = buf.Bytes()
+	}
+
+	b := decoded.Bounds()
+	return &Image{Data: stripped, MIMEType: "image/jpeg", Width: b.Dx(), Height: b.Dy()}, decoded, nil
+}
+
+func processPNG(data []byte) (*Image, image.Image, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, 1); err != nil {
+		return nil, nil, err
+	}
+
+	stripped, err := stripPNG(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoded, err := png.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	return &Image{Data: stripped, MIMEType: "image/png", Width: cfg.Width, Height: cfg.Height}, decoded, nil
+}
+
+func processGIF(data []byte) (*Image, image.Image, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	// Animated GIFs are decoded frame by frame: count them before decoding
+	frames, err := gifFrames(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, frames); err != nil {
+		return nil, nil, err
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	// Encoding the decoded frames again drops the comments and the application extensions (e.g., XMP), keeping the
+	// pixels, the timing and the loop count
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, nil, err
+	}
+
+	// Thumbnails show the first frame
+	return &Image{Data: buf.Bytes(), MIMEType: "image/gif", Width: cfg.Width, Height: cfg.Height}, firstFrame(g), nil
+}
+
+func processWebP(data []byte) (*Image, error) {
+	width, height, stripped, err := stripWebP(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDimensions(width, height, 1); err != nil {
+		return nil, err
+	}
+	return &Image{Data: stripped, MIMEType: "image/webp", Width: width, Height: height}, nil
+}
+
+// makeThumbnail reduces img so that its longest side is size pixels. Opaque images are encoded as JPEG, the others as
+// PNG to keep the transparency.
+func makeThumbnail(img image.Image, size int) (Thumbnail, error) {
+	b := img.Bounds()
+	width, height := size, size
+	if b.Dx() > b.Dy() {
+		height = (b.Dy()*size + b.Dx()/2) / b.Dx()
+	} else {
+		width = (b.Dx()*size + b.Dy()/2) / b.Dy()
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	reduced := resize(img, width, height)
+
+	t := Thumbnail{Size: size, Width: width, Height: height}
+	var buf bytes.Buffer
+	if reduced.Opaque() {
+		t.MIMEType = "image/jpeg"
+		if err := jpeg.Encode(&buf, reduced, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return t, err
+		}
+	} else {
+		t.MIMEType = "image/png"
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, reduced); err != nil {
+			return t, err
+		}
+	}
+	t.Data = buf.Bytes()
+	return t, nil
+}
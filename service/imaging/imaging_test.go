@@ -0,0 +1,269 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// testImage returns an image with a gradient, opaque or with a transparent half.
+func testImage(width int, height int, opaque bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255}
+			if !opaque && x < width/2 {
+				c.A = 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func testPNG(t *testing.T, width int, height int, opaque bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(width, height, opaque)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngChunk returns a PNG chunk, with its checksum.
+func pngChunk(typ string, payload string) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, typ+payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// afterIHDR inserts chunks after the IHDR chunk of a PNG file (the first one, right after the signature).
+func afterIHDR(data []byte, chunks ...[]byte) []byte {
+	const ihdrEnd = 8 + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, data[ihdrEnd:]...)
+}
+
+func TestStripPNG(t *testing.T) {
+	original := testPNG(t, 32, 16, true)
+	phys := pngChunk("pHYs", "\x00\x00\x0b\x13\x00\x00\x0b\x13\x01")
+	data := afterIHDR(original,
+		pngChunk("tEXt", "Comment\x00GPS-SECRET"),
+		pngChunk("eXIf", "MM\x00\x2aGPS-SECRET"),
+		phys,
+		pngChunk("prVt", "GPS-SECRET"), // unknown ancillary chunk
+	)
+	data = append(data, pngChunk("tEXt", "Comment\x00GPS-SECRET after the end")...)
+
+	stripped, err := stripPNG(data)
+	if err != nil {
+		t.Fatalf("stripPNG: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("GPS-SECRET")) {
+		t.Error("metadata left in the stripped image")
+	}
+	if want := afterIHDR(original, phys); !bytes.Equal(stripped, want) {
+		t.Errorf("stripped image is %d bytes, want the original (%d bytes) with the pHYs chunk", len(stripped), len(want))
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("decoding the stripped image: %v", err)
+	}
+
+	if _, err := stripPNG(original[:len(original)-4]); !errors.Is(err, ErrInvalid) {
+		t.Errorf("truncated PNG: stripPNG = %v, want ErrInvalid", err)
+	}
+}
+
+// webpChunk returns a RIFF chunk, padded to an even size.
+func webpChunk(fourCC string, payload string) []byte {
+	chunk := append([]byte(fourCC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// riff returns a WebP file made of the given chunks.
+func riff(chunks ...[]byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		data = append(data, c...)
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+func TestProcessWebP(t *testing.T) {
+	// Extended format, 100x50 canvas, with EXIF and XMP
+	vp8x := webpChunk("VP8X", string([]byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 99, 0, 0, 49, 0, 0}))
+	frame := webpChunk("VP8L", "\x2f\x63\x40\x0c\x00 image data")
+	data := riff(vp8x, webpChunk("EXIF", "MM\x00\x2aGPS-SECRET"), frame, webpChunk("XMP ", "<x:xmpmeta>GPS-SECRET</x:xmpmeta>"))
+	data = append(data, "GPS-SECRET after the end"...)
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if img.MIMEType != "image/webp" || img.Width != 100 || img.Height != 50 || len(img.Thumbnails) != 0 {
+		t.Errorf("Process = %s %dx%d with %d thumbnails, want image/webp 100x50 without thumbnails",
+			img.MIMEType, img.Width, img.Height, len(img.Thumbnails))
+	}
+	if bytes.Contains(img.Data, []byte("GPS-SECRET")) {
+		t.Error("metadata left in the stripped image")
+	}
+	vp8x[8] = 0 // the metadata flags are cleared
+	if want := riff(vp8x, frame); !bytes.Equal(img.Data, want) {
+		t.Errorf("stripped image = %q, want %q", img.Data, want)
+	}
+
+	// Simple format: the dimensions come from the image chunk (14 bits each, minus one)
+	data = riff(webpChunk("VP8L", "\x2f\x3f\x80\x0f\x00"))
+	if img, err := Process(data); err != nil || img.Width != 64 || img.Height != 63 {
+		t.Errorf("Process = %v, %v, want 64x63", img, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated":       riff(vp8x)[:20],
+		"no image":        riff(),
+		"unknown first":   riff(webpChunk("ALPH", "alpha"), frame),
+		"size past chunk": riff(vp8x, []byte("VP8L\xff\x00\x00\x00")),
+	} {
+		if _, err := Process(data); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Process = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+// testGIF encodes an animation with the given number of frames of 1x1 pixel, on a canvas of the given size.
+func testGIF(t *testing.T, width int, height int, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{Config: image.Config{ColorModel: color.Palette(palette.Plan9), Width: width, Height: height}}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(i%width, 0, i%width+1, 1), palette.Plan9)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessGIF(t *testing.T) {
+	// A comment before the trailer
+	data := testGIF(t, 80, 40, 3)
+	data = append(data[:len(data)-1], "\x21\xfe\x0aGPS-SECRET\x00\x3b"...)
+	if frames, err := gifFrames(data); err != nil || frames != 3 {
+		t.Errorf("gifFrames = %d, %v, want 3", frames, err)
+	}
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if img.MIMEType != "image/gif" || img.Width != 80 || img.Height != 40 {
+		t.Errorf("Process = %s %dx%d, want image/gif 80x40", img.MIMEType, img.Width, img.Height)
+	}
+	if bytes.Contains(img.Data, []byte("GPS-SECRET")) {
+		t.Error("comment left in the stored image")
+	}
+	if g, err := gif.DecodeAll(bytes.NewReader(img.Data)); err != nil || len(g.Image) != 3 {
+		t.Errorf("stored image: %v, want 3 frames", err)
+	}
+	if len(img.Thumbnails) != 1 || img.Thumbnails[0].Width != 64 || img.Thumbnails[0].Height != 32 {
+		t.Errorf("thumbnails = %+v, want a 64x32 one", img.Thumbnails)
+	}
+
+	// The pixels of all the frames count
+	if _, err := Process(testGIF(t, 2000, 2000, 11)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("11 frames of 2000x2000: Process = %v, want ErrTooLarge", err)
+	}
+	if _, err := gifFrames(data[:len(data)-1]); !errors.Is(err, ErrInvalid) {
+		t.Errorf("truncated GIF: gifFrames = %v, want ErrInvalid", err)
+	}
+}
+
+func TestProcessThumbnails(t *testing.T) {
+	// Opaque images have JPEG thumbnails, in the sizes smaller than the image
+	img, err := Process(testPNG(t, 300, 200, true))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	want := []Thumbnail{
+		{Size: 64, MIMEType: "image/jpeg", Width: 64, Height: 43},
+		{Size: 256, MIMEType: "image/jpeg", Width: 256, Height: 171},
+	}
+	if len(img.Thumbnails) != len(want) {
+		t.Fatalf("%d thumbnails, want %d", len(img.Thumbnails), len(want))
+	}
+	for i, thumbnail := range img.Thumbnails {
+		data := thumbnail.Data
+		thumbnail.Data = nil
+		if thumbnail.Size != want[i].Size || thumbnail.MIMEType != want[i].MIMEType ||
+			thumbnail.Width != want[i].Width || thumbnail.Height != want[i].Height {
+			t.Errorf("thumbnail %d = %+v, want %+v", i, thumbnail, want[i])
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width != want[i].Width || cfg.Height != want[i].Height {
+			t.Errorf("thumbnail %d: %dx%d, %v", i, cfg.Width, cfg.Height, err)
+		}
+	}
+
+	// Transparent images have PNG thumbnails; a portrait gets the size on its height
+	img, err = Process(testPNG(t, 40, 100, false))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if len(img.Thumbnails) != 1 || img.Thumbnails[0].MIMEType != "image/png" ||
+		img.Thumbnails[0].Width != 26 || img.Thumbnails[0].Height != 64 {
+		t.Errorf("thumbnails = %+v, want a 26x64 PNG", img.Thumbnails)
+	}
+
+	// Small images have none
+	if img, err := Process(testPNG(t, 64, 64, true)); err != nil || len(img.Thumbnails) != 0 {
+		t.Errorf("64x64 image: %v, %v, want no thumbnails", img, err)
+	}
+}
+
+func TestProcessInvalid(t *testing.T) {
+	// A PNG claiming to be 10000x10000, which would take 400 MB once decoded
+	bomb := testPNG(t, 1, 1, true)
+	ihdr := []byte(bomb[8+8 : 8+8+13])
+	binary.BigEndian.PutUint32(ihdr, 10000)
+	binary.BigEndian.PutUint32(ihdr[4:], 10000)
+	bomb = append(append(append([]byte{}, bomb[:8]...), pngChunk("IHDR", string(ihdr))...), bomb[8+12+13:]...)
+	if _, err := Process(bomb); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("PNG bomb: Process = %v, want ErrTooLarge", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"BMP", []byte("BM\x46\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), ErrUnsupported},
+		{"text", []byte("GPS-SECRET"), ErrUnsupported},
+		{"empty", nil, ErrUnsupported},
+		{"broken PNG", []byte("\x89PNG\r\n\x1a\nGPS-SECRET"), ErrInvalid},
+		{"broken JPEG", []byte("\xff\xd8\xff\xe0GPS-SECRET"), ErrInvalid},
+		{"broken GIF", []byte("GIF89aGPS-SECRET"), ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Process = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
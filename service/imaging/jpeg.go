@@ -0,0 +1,225 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// JPEG markers, see https://www.w3.org/Graphics/JPEG/itu-t81.pdf (table B.1).
+const (
+	jpegSOI  = 0xd8
+	jpegEOI  = 0xd9
+	jpegSOS  = 0xda
+	jpegTEM  = 0x01
+	jpegRST0 = 0xd0
+	jpegRST7 = 0xd7
+	jpegAPP0 = 0xe0
+	jpegAPP1 = 0xe1
+	jpegAPP2 = 0xe2
+	jpegAPPE = 0xee
+	jpegAPPF = 0xef
+	jpegCOM  = 0xfe
+)
+
+// jpegSegment is a marker segment of the header of a JPEG file. data is the whole segment, including the marker.
+type jpegSegment struct {
+	marker  byte
+	payload []byte
+	data    []byte
+}
+
+// jpegSegments splits a JPEG file into the segments before the scan data, and the rest of the file (from the first SOS
+// segment on).
+func jpegSegments(data []byte) ([]jpegSegment, []byte, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != jpegSOI {
+		return nil, nil, fmt.Errorf("%w: missing JPEG start marker", ErrInvalid)
+	}
+
+	var segments []jpegSegment
+	pos := 2
+	for {
+		// Markers may be preceded by any number of fill bytes (0xff)
+		start := pos
+		for pos < len(data) && data[pos] == 0xff {
+			pos++
+		}
+		if pos == start || pos >= len(data) {
+			return nil, nil, fmt.Errorf("%w: truncated JPEG header", ErrInvalid)
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == jpegSOS:
+			return segments, data[start:], nil
+		case marker == jpegEOI:
+			return nil, nil, fmt.Errorf("%w: JPEG without image data", ErrInvalid)
+		case marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7):
+			// Markers without a payload
+			segments = append(segments, jpegSegment{marker: marker, data: data[start:pos]})
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, nil, fmt.Errorf("%w: truncated JPEG header", ErrInvalid)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, nil, fmt.Errorf("%w: invalid JPEG segment length", ErrInvalid)
+		}
+		segments = append(segments, jpegSegment{marker: marker, payload: data[pos+2 : pos+length], data: data[start : pos+length]})
+		pos += length
+	}
+}
+
+// jpegScans splits the rest of a JPEG file, from the first SOS segment on, into segments up to the end of the image
+// (EOI) included. The data of a SOS segment includes the entropy-coded data that follows it, up to the next marker
+// (restart markers are part of the data). Anything after EOI is not returned: it's not part of the image, and it can
+// hide anything, such as another copy of the metadata.
+func jpegScans(data []byte) ([]jpegSegment, error) {
+	var segments []jpegSegment
+	pos := 0
+	for {
+		start := pos
+		for pos < len(data) && data[pos] == 0xff {
+			pos++
+		}
+		if pos == start || pos >= len(data) {
+			return nil, fmt.Errorf("%w: truncated JPEG image data", ErrInvalid)
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == jpegEOI:
+			return append(segments, jpegSegment{marker: marker, data: data[start:pos]}), nil
+		case marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7):
+			segments = append(segments, jpegSegment{marker: marker, data: data[start:pos]})
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, fmt.Errorf("%w: truncated JPEG image data", ErrInvalid)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, fmt.Errorf("%w: invalid JPEG segment length", ErrInvalid)
+		}
+		payload := data[pos+2 : pos+length]
+		pos += length
+
+		if marker == jpegSOS {
+			pos = jpegScanEnd(data, pos)
+		}
+		segments = append(segments, jpegSegment{marker: marker, payload: payload, data: data[start:pos]})
+	}
+}
+
+// jpegScanEnd returns the position of the marker ending the entropy-coded data that starts at pos, or the length of
+// data if there is none. In entropy-coded data, 0xff is followed by 0x00 (a stuffed byte) or by a restart marker.
+func jpegScanEnd(data []byte, pos int) int {
+	for ; pos+1 < len(data); pos++ {
+		if data[pos] != 0xff {
+			continue
+		}
+		if next := data[pos+1]; next != 0 && (next < jpegRST0 || next > jpegRST7) {
+			return pos
+		}
+		pos++
+	}
+	return len(data)
+}
+
+// keepJPEGSegment reports whether a segment is kept by stripJPEG: all but the application segments other than JFIF
+// (APP0), the ICC color profile (APP2) and the Adobe color transform (APP14), which affect how the image looks, and the
+// comments.
+func keepJPEGSegment(s jpegSegment) bool {
+	switch {
+	case s.marker == jpegCOM:
+		return false
+	case s.marker >= jpegAPP0 && s.marker <= jpegAPPF:
+		return s.marker == jpegAPP0 || s.marker == jpegAPPE ||
+			(s.marker == jpegAPP2 && bytes.HasPrefix(s.payload, []byte("ICC_PROFILE\x00")))
+	default:
+		return true
+	}
+}
+
+// stripJPEG removes the metadata segments from a JPEG file, without decoding it (see keepJPEGSegment), both in the
+// header and between the scans of progressive images, and drops anything after the end of the image.
+func stripJPEG(data []byte) ([]byte, error) {
+	segments, rest, err := jpegSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	scans, err := jpegScans(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, jpegSOI)
+	for _, s := range append(segments, scans...) {
+		if keepJPEGSegment(s) {
+			out = append(out, s.data...)
+		}
+	}
+	return out, nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG file (1 to 8), or 1 if it is missing or invalid. See
+// https://www.cipa.jp/std/documents/e/DC-008-2012_E.pdf (tag 0x0112).
+func jpegOrientation(data []byte) int {
+	segments, _, err := jpegSegments(data)
+	if err != nil {
+		return 1
+	}
+	for _, s := range segments {
+		if s.marker == jpegAPP1 && bytes.HasPrefix(s.payload, []byte("Exif\x00\x00")) {
+			return exifOrientation(s.payload[6:])
+		}
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF structure, as embedded in EXIF.
+func exifOrientation(tiff []byte) int {
+	const orientationTag = 0x0112
+	const shortType = 3
+
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int64(order.Uint32(tiff[4:]))
+	if ifd+2 > int64(len(tiff)) {
+		return 1
+	}
+	entries := int64(order.Uint16(tiff[ifd:]))
+	for i := int64(0); i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag && order.Uint16(tiff[entry+2:]) == shortType {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
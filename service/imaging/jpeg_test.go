@@ -0,0 +1,194 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testJPEG encodes a noisy image, so that the entropy-coded data has stuffed bytes.
+func testJPEG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegSegmentBytes returns a marker segment with the given payload.
+func jpegSegmentBytes(marker byte, payload string) []byte {
+	s := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+// exifPayload returns an APP1 EXIF payload with the orientation tag and a text that stands for private metadata.
+func exifPayload(orientation uint16) string {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08" + // header, first IFD at 8
+		"\x00\x01" + // one entry
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00" + // orientation, SHORT, 1 value
+		"\x00\x00\x00\x00" + // no next IFD
+		"GPS-SECRET")
+	binary.BigEndian.PutUint16(tiff[18:], orientation)
+	return "Exif\x00\x00" + string(tiff)
+}
+
+// insertJPEG inserts header segments after SOI, and appends trailer after EOI.
+func insertJPEG(data []byte, header [][]byte, beforeEOI []byte, trailer []byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, s := range header {
+		out = append(out, s...)
+	}
+	out = append(out, data[2:len(data)-2]...)
+	out = append(out, beforeEOI...)
+	out = append(out, 0xff, jpegEOI)
+	return append(out, trailer...)
+}
+
+func TestStripJPEG(t *testing.T) {
+	original := testJPEG(t, 64, 48)
+	if !bytes.Contains(original, []byte{0xff, 0x00}) {
+		t.Fatal("test image without stuffed bytes")
+	}
+
+	data := insertJPEG(original,
+		[][]byte{
+			jpegSegmentBytes(jpegAPP1, exifPayload(1)),
+			jpegSegmentBytes(jpegCOM, "GPS-SECRET comment"),
+			jpegSegmentBytes(jpegAPP2, "ICC_PROFILE\x00\x01\x01profile"),
+			jpegSegmentBytes(jpegAPP1, "http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>GPS-SECRET</x:xmpmeta>"),
+		},
+		jpegSegmentBytes(jpegCOM, "GPS-SECRET after the scan"),
+		jpegSegmentBytes(jpegAPP1, exifPayload(1)+" after the end"),
+	)
+	stripped, err := stripJPEG(data)
+	if err != nil {
+		t.Fatalf("stripJPEG: %v", err)
+	}
+
+	if bytes.Contains(stripped, []byte("GPS-SECRET")) {
+		t.Error("metadata left in the stripped image")
+	}
+	if !bytes.Contains(stripped, []byte("ICC_PROFILE\x00")) {
+		t.Error("color profile removed")
+	}
+	if !bytes.HasSuffix(stripped, []byte{0xff, jpegEOI}) {
+		t.Error("stripped image does not end with EOI")
+	}
+	// Exactly the original image, plus the color profile
+	if want := len(original) + len(jpegSegmentBytes(jpegAPP2, "ICC_PROFILE\x00\x01\x01profile")); len(stripped) != want {
+		t.Errorf("stripped image is %d bytes, want %d", len(stripped), want)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("decoding the stripped image: %v", err)
+	}
+}
+
+func TestStripJPEGInvalid(t *testing.T) {
+	original := testJPEG(t, 16, 16)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no start marker", original[2:]},
+		{"truncated header", original[:20]},
+		{"no end marker", original[:len(original)-2]},
+		{"truncated scan", original[:len(original)-40]},
+		{"no image data", []byte{0xff, jpegSOI, 0xff, jpegEOI}},
+	}
+	for _, tt := range tests {
+		if _, err := stripJPEG(tt.data); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: stripJPEG = %v, want ErrInvalid", tt.name, err)
+		}
+	}
+}
+
+func TestJPEGScanEnd(t *testing.T) {
+	// Stuffed byte and restart marker within the data, then fill bytes before EOI
+	data := []byte{0x12, 0xff, 0x00, 0x34, 0xff, jpegRST0, 0x56, 0xff, 0xff, jpegEOI}
+	if got := jpegScanEnd(data, 0); got != 7 {
+		t.Errorf("jpegScanEnd = %d, want 7", got)
+	}
+	if got := jpegScanEnd(data[:7], 0); got != 7 {
+		t.Errorf("jpegScanEnd without a marker = %d, want 7", got)
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	original := testJPEG(t, 40, 20)
+	tests := []struct {
+		exif        uint16
+		orientation int
+		rotated     bool
+	}{
+		{1, 1, false},
+		{3, 3, false},
+		{6, 6, true},
+		{8, 8, true},
+		{9, 1, false}, // invalid
+	}
+	for _, tt := range tests {
+		data := insertJPEG(original, [][]byte{jpegSegmentBytes(jpegAPP1, exifPayload(tt.exif))}, nil, nil)
+		if got := jpegOrientation(data); got != tt.orientation {
+			t.Errorf("EXIF orientation %d: jpegOrientation = %d, want %d", tt.exif, got, tt.orientation)
+		}
+
+		img, err := Process(data)
+		if err != nil {
+			t.Fatalf("EXIF orientation %d: Process: %v", tt.exif, err)
+		}
+		width, height := 40, 20
+		if tt.rotated {
+			width, height = height, width
+		}
+		if img.Width != width || img.Height != height {
+			t.Errorf("EXIF orientation %d: %dx%d, want %dx%d", tt.exif, img.Width, img.Height, width, height)
+		}
+		if bytes.Contains(img.Data, []byte("GPS-SECRET")) {
+			t.Errorf("EXIF orientation %d: metadata left", tt.exif)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image, red then blue, in all the orientations
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width       int
+		first       color.RGBA // at (0, 0)
+	}{
+		{1, 2, red},
+		{2, 2, blue},
+		{3, 2, blue},
+		{4, 2, red},
+		{5, 1, red},
+		{6, 1, red},
+		{7, 1, blue},
+		{8, 1, blue},
+	}
+	for _, tt := range tests {
+		got := orient(img, tt.orientation)
+		if got.Bounds().Dx() != tt.width {
+			t.Errorf("orientation %d: width %d, want %d", tt.orientation, got.Bounds().Dx(), tt.width)
+		}
+		if c := got.RGBAAt(0, 0); c != tt.first {
+			t.Errorf("orientation %d: first pixel %v, want %v", tt.orientation, c, tt.first)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// pngKeptChunks are the ancillary PNG chunks kept by stripPNG, as they affect how the image looks (transparency,
+// colors, pixel size, animation). Text, time and EXIF chunks, and unknown ones, are removed.
+var pngKeptChunks = map[string]bool{
+	"tRNS": true,
+	"gAMA": true,
+	"cHRM": true,
+	"sRGB": true,
+	"iCCP": true,
+	"sBIT": true,
+	"bKGD": true,
+	"pHYs": true,
+	"acTL": true,
+	"fcTL": true,
+	"fdAT": true,
+}
+
+// stripPNG removes the metadata chunks from a PNG file, without decoding it. Critical chunks (whose type starts with
+// an uppercase letter) are always kept. See https://www.w3.org/TR/png/#5Chunk-layout.
+func stripPNG(data []byte) ([]byte, error) {
+	const signatureLength = 8
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:signatureLength]...)
+	pos := signatureLength
+	for {
+		if pos+8 > len(data) {
+			return nil, fmt.Errorf("%w: truncated PNG", ErrInvalid)
+		}
+		length := int64(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		end := int64(pos) + 12 + length
+		if end > int64(len(data)) {
+			return nil, fmt.Errorf("%w: truncated PNG chunk %q", ErrInvalid, typ)
+		}
+
+		critical := typ[0] >= 'A' && typ[0] <= 'Z'
+		if critical || pngKeptChunks[typ] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = int(end)
+		if typ == "IEND" {
+			// Anything after the end of the image is dropped too
+			return out, nil
+		}
+	}
+}
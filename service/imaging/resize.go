@@ -0,0 +1,141 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// toRGBA returns the pixels of img as RGBA, with the origin at (0, 0).
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// orient transforms img as described by an EXIF orientation (2 to 8), so that it's displayed upright.
+func orient(img image.Image, orientation int) *image.RGBA {
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// Rotated by 90 degrees
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated by 180 degrees
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // mirrored along the main diagonal
+				sx, sy = y, x
+			case 6: // rotated by 90 degrees counterclockwise: rotate clockwise
+				sx, sy = y, h-1-x
+			case 7: // mirrored along the other diagonal
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated by 90 degrees clockwise: rotate counterclockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// resize scales img down to width x height pixels. Each destination pixel is the average of the source pixels it
+// covers, weighted by the covered area, which gives smooth results when reducing by large factors.
+func resize(img image.Image, width int, height int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+
+	// Premultiplied alpha (as in image.RGBA) keeps transparent pixels from bleeding their color
+	xWeights := boxWeights(sw, width)
+	tmp := make([]float32, width*sh*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, weights := range xWeights {
+			var acc [4]float32
+			for _, w := range weights {
+				p := row[w.index*4:]
+				acc[0] += float32(p[0]) * w.weight
+				acc[1] += float32(p[1]) * w.weight
+				acc[2] += float32(p[2]) * w.weight
+				acc[3] += float32(p[3]) * w.weight
+			}
+			copy(tmp[(y*width+x)*4:], acc[:])
+		}
+	}
+
+	yWeights := boxWeights(sh, height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, weights := range yWeights {
+		for x := 0; x < width; x++ {
+			var acc [4]float32
+			for _, w := range weights {
+				p := tmp[(w.index*width+x)*4:]
+				acc[0] += p[0] * w.weight
+				acc[1] += p[1] * w.weight
+				acc[2] += p[2] * w.weight
+				acc[3] += p[3] * w.weight
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			for i := range acc {
+				d[i] = clamp(acc[i])
+			}
+		}
+	}
+	return dst
+}
+
+// boxWeight is the contribution of a source pixel to a destination pixel.
+type boxWeight struct {
+	index  int
+	weight float32
+}
+
+// boxWeights returns, for each of the dst pixels of a line, the source pixels it covers when scaling src pixels to
+// dst (dst <= src), with their weights (summing to 1).
+func boxWeights(src int, dst int) [][]boxWeight {
+	scale := float64(src) / float64(dst)
+	weights := make([][]boxWeight, dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			// Overlap between the source pixel [j, j+1) and [start, end)
+			lo, hi := float64(j), float64(j+1)
+			if start > lo {
+				lo = start
+			}
+			if end < hi {
+				hi = end
+			}
+			if hi > lo {
+				weights[i] = append(weights[i], boxWeight{index: j, weight: float32((hi - lo) / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+func clamp(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
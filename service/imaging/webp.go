@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// WebP metadata flags of the VP8X chunk.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP removes the EXIF and XMP chunks from a WebP file, and returns it with the dimensions of the image. See
+// https://developers.google.com/speed/webp/docs/riff_container.
+func stripWebP(data []byte) (width int, height int, stripped []byte, err error) {
+	const headerLength = 12
+	truncated := fmt.Errorf("%w: truncated WebP", ErrInvalid)
+
+	riffEnd := 8 + int64(binary.LittleEndian.Uint32(data[4:]))
+	if riffEnd > int64(len(data)) {
+		return 0, 0, nil, truncated
+	}
+
+	out := make([]byte, headerLength, len(data))
+	copy(out, data[:headerLength])
+	pos := int64(headerLength)
+	first := true
+	for pos < riffEnd {
+		if pos+8 > riffEnd {
+			return 0, 0, nil, truncated
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int64(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2 // chunks are padded to an even size
+		if pos+8+size > riffEnd {
+			return 0, 0, nil, truncated
+		}
+		if end > riffEnd {
+			end = riffEnd
+		}
+		payload := data[pos+8 : pos+8+size]
+
+		if first {
+			width, height, err = webpSize(fourCC, payload)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			first = false
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[pos:end]...)
+			out[start+8] &^= webpFlagEXIF | webpFlagXMP
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	if first {
+		return 0, 0, nil, fmt.Errorf("%w: WebP without image data", ErrInvalid)
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return width, height, out, nil
+}
+
+// webpSize reads the dimensions of the image from the first chunk of a WebP file.
+func webpSize(fourCC string, payload []byte) (int, int, error) {
+	invalid := fmt.Errorf("%w: invalid WebP %q chunk", ErrInvalid, fourCC)
+	uint24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+
+	switch fourCC {
+	case "VP8X":
+		// Flags (4 bytes), canvas width - 1 (3 bytes), canvas height - 1 (3 bytes)
+		if len(payload) < 10 {
+			return 0, 0, invalid
+		}
+		return uint24(payload[4:]) + 1, uint24(payload[7:]) + 1, nil
+
+	case "VP8 ":
+		// Frame tag (3 bytes), start code, width and height (14 bits each, with 2 bits of scale)
+		if len(payload) < 10 || payload[3] != 0x9d || payload[4] != 0x01 || payload[5] != 0x2a {
+			return 0, 0, invalid
+		}
+		return int(binary.LittleEndian.Uint16(payload[6:]) & 0x3fff), int(binary.LittleEndian.Uint16(payload[8:]) & 0x3fff), nil
+
+	case "VP8L":
+		// Signature, then width - 1 and height - 1 (14 bits each)
+		if len(payload) < 5 || payload[0] != 0x2f {
+			return 0, 0, invalid
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, nil
+
+	default:
+		return 0, 0, invalid
+	}
+}